@@ -5,8 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -29,24 +32,56 @@ const (
 	CheckoutPath
 	SearchPath
 	TermsPath
+	RevisionsPath
+	SchedulePath
+	DomainsPath
+	PagesPath
+	ExportPath
+	MarkdownPath
+	FeedsPath
+	SitemapPath
+	SitemapsPath
+	RobotsPath
+	MetricsPath
+	AnalyticsPath
+	TagsPath
+	ReportPath
+	ModerationPath
+	AdminPath
 )
 
 var Endpoints = map[Endpoint]string{
-	RootPath:      "/",
-	AssetsPath:    "assets/",
-	EditorPath:    "editor/",
-	DashboardPath: "dashboard",
-	RegisterPath:  "register",
-	LoginPath:     "login",
-	LogoutPath:    "logout",
-	PricingPath:   "pricing",
-	AccountPath:   "account/",
-	UploadPath:    "upload/",
-	SettingsPath:  "settings/",
-	BannerPath:    "banner/",
-	CheckoutPath:  "checkout/",
-	SearchPath:    "search",
-	TermsPath:     "terms",
+	RootPath:       "/",
+	AssetsPath:     "assets/",
+	EditorPath:     "editor/",
+	DashboardPath:  "dashboard",
+	RegisterPath:   "register",
+	LoginPath:      "login",
+	LogoutPath:     "logout",
+	PricingPath:    "pricing",
+	AccountPath:    "account/",
+	UploadPath:     "upload/",
+	SettingsPath:   "settings/",
+	BannerPath:     "banner/",
+	CheckoutPath:   "checkout/",
+	SearchPath:     "search",
+	TermsPath:      "terms",
+	RevisionsPath:  "revisions/",
+	SchedulePath:   "schedule/",
+	DomainsPath:    "domains/",
+	PagesPath:      "pages/",
+	ExportPath:     "export/",
+	MarkdownPath:   "markdown/",
+	FeedsPath:      "feeds/",
+	SitemapPath:    "sitemap.xml",
+	SitemapsPath:   "sitemaps/",
+	RobotsPath:     "robots.txt",
+	MetricsPath:    "debug/vars",
+	AnalyticsPath:  "analytics/",
+	TagsPath:       "tags/",
+	ReportPath:     "report/",
+	ModerationPath: "moderation/",
+	AdminPath:      "admin/",
 }
 
 var (
@@ -75,12 +110,54 @@ var (
 	ServerSMTPPass string
 
 	ServerSecret string
+	// True when no secret was set and a random one was generated, sessions
+	// and unique visitor counts then do not survive a restart
+	ServerSecretGenerated bool
 
 	PayPalClientID         string
 	PayPalClientSecret     string
 	PayPalEndpoint         string  = "https://api-m.paypal.com"
 	PayPalPurchaseValueStr string  = "20.00"
 	PayPalPurchaseValue    float32 = 20.00
+
+	// HTTP caching, Cache-Control policies for public site pages and for files
+	// under AssetsPath. Site pages must be revalidated for visits to be counted
+
+	SiteCacheControl   string = "public, no-cache"
+	AssetsCacheControl string = "public, max-age=86400"
+
+	// Maximum number of rendered site pages kept in memory, 0 disables it
+	PageCacheSize int = 512
+
+	// Bearer token that lets monitoring read MetricsPath without an admin
+	// session, only admins can read them when empty
+	MetricsToken string
+
+	// How often buffered site visits are written to the database, visits
+	// still buffered are written on shutdown
+	VisitsFlushInterval time.Duration = 10 * time.Second
+
+	// Emails of the users promoted to admins on start, admins can then grant
+	// roles to other users from the admin console
+	Admins []string
+
+	// Directory with the <lang>.txt moderation wordlists, the default lists
+	// are used when empty
+	ModerationDir string
+
+	// Hostnames the app itself is served under, requests to them are never
+	// matched against custom domains
+	Hosts []string = []string{"localhost"}
+
+	// Addresses or ranges of the reverse proxies in front of the app, the
+	// X-Forwarded-* headers are ignored on requests from anyone else
+
+	TrustedProxies []netip.Prefix
+
+	// Crawlers
+
+	RobotsDisallowAll bool
+	RobotsDisallow    []string
 )
 
 const (
@@ -111,6 +188,20 @@ const (
 	envPayPalClientSecret     = envPrefix + "PP_CLIENT_SECRET"
 	envPayPalEndpoint         = envPrefix + "PP_ENDPOINT"
 	envPayPalPurchaseValueStr = envPrefix + "PP_VALUE"
+
+	envSiteCacheControl   = envPrefix + "SITE_CACHE_CONTROL"
+	envAssetsCacheControl = envPrefix + "ASSETS_CACHE_CONTROL"
+	envPageCacheSize      = envPrefix + "PAGE_CACHE_SIZE"
+	envMetricsToken       = envPrefix + "METRICS_TOKEN"
+	envVisitsFlush        = envPrefix + "VISITS_FLUSH_INTERVAL"
+	envModerationDir      = envPrefix + "MODERATION_DIR"
+	envAdmins             = envPrefix + "ADMINS"
+
+	envHosts          = envPrefix + "HOSTS"
+	envTrustedProxies = envPrefix + "TRUSTED_PROXIES"
+
+	envRobotsDisallowAll = envPrefix + "ROBOTS_DISALLOW_ALL"
+	envRobotsDisallow    = envPrefix + "ROBOTS_DISALLOW"
 )
 
 func Init() {
@@ -194,6 +285,7 @@ func Init() {
 
 	if ServerSecret == "" {
 		ServerSecret = generateRandomSecret(32)
+		ServerSecretGenerated = true
 	}
 
 	logLevelStr := os.Getenv(envLog)
@@ -209,6 +301,71 @@ func Init() {
 	if conn != "" {
 		dbConn = conn
 	}
+
+	scc := os.Getenv(envSiteCacheControl)
+	if scc != "" {
+		SiteCacheControl = scc
+	}
+
+	acc := os.Getenv(envAssetsCacheControl)
+	if acc != "" {
+		AssetsCacheControl = acc
+	}
+
+	if pcs, err := strconv.Atoi(os.Getenv(envPageCacheSize)); err == nil {
+		PageCacheSize = pcs
+	}
+
+	MetricsToken = os.Getenv(envMetricsToken)
+
+	if vfi, err := time.ParseDuration(os.Getenv(envVisitsFlush)); err == nil && vfi > 0 {
+		VisitsFlushInterval = vfi
+	}
+
+	ModerationDir = os.Getenv(envModerationDir)
+
+	// Comma separated list of admin emails
+	for email := range strings.SplitSeq(os.Getenv(envAdmins), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			Admins = append(Admins, strings.ToLower(email))
+		}
+	}
+
+	// Comma separated list of hostnames
+	if hosts := os.Getenv(envHosts); hosts != "" {
+		Hosts = nil
+		for host := range strings.SplitSeq(hosts, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				Hosts = append(Hosts, strings.ToLower(host))
+			}
+		}
+	}
+
+	// Comma separated list of proxy addresses or CIDR ranges
+	for proxy := range strings.SplitSeq(os.Getenv(envTrustedProxies), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				panic("Invalid trusted proxy " + proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		TrustedProxies = append(TrustedProxies, prefix.Masked())
+	}
+
+	RobotsDisallowAll = os.Getenv(envRobotsDisallowAll) == "1"
+
+	// Comma separated list of extra paths hidden from crawlers
+	for path := range strings.SplitSeq(os.Getenv(envRobotsDisallow), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			RobotsDisallow = append(RobotsDisallow, path)
+		}
+	}
 }
 
 func generateRandomSecret(n int) string {
@@ -0,0 +1,76 @@
+// Package cursor implements opaque, signed tokens for keyset pagination.
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor holds the sort keys of the last row of a page, in the order named by
+// Order. Keys the order does not sort by are ignored. Page counts the pages
+// served so far.
+type Cursor struct {
+	Order   string  `json:"o"`
+	Page    int     `json:"p"`
+	Site    int64   `json:"s"`
+	Visits  int64   `json:"v,omitempty"`
+	Score   float64 `json:"t,omitempty"`
+	Rank    int64   `json:"r,omitempty"`
+	Created int64   `json:"c,omitempty"`
+}
+
+// Encode signs c with secret and returns it as a URL safe token.
+func Encode(secret string, c Cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(sign(secret, payload)), nil
+}
+
+// Decode verifies token was signed with secret for order and returns its
+// cursor.
+func Decode(secret, token, order string) (Cursor, error) {
+	enc := base64.RawURLEncoding
+
+	payloadStr, sigStr, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalid
+	}
+
+	payload, err := enc.DecodeString(payloadStr)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	sig, err := enc.DecodeString(sigStr)
+	if err != nil || !hmac.Equal(sig, sign(secret, payload)) {
+		return Cursor{}, ErrInvalid
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	if c.Order != order || c.Page < 1 {
+		return Cursor{}, ErrInvalid
+	}
+
+	return c, nil
+}
+
+func sign(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("cursor:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
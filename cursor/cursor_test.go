@@ -0,0 +1,159 @@
+package cursor
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const secret = "secret"
+
+func TestRoundTrip(t *testing.T) {
+	want := Cursor{
+		Order:   "home:trending",
+		Page:    3,
+		Site:    42,
+		Visits:  1000,
+		Score:   0.0607927101854,
+		Rank:    90,
+		Created: 1767225600,
+	}
+
+	token, err := Encode(secret, want)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got, err := Decode(secret, token, want.Order)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	enc := base64.RawURLEncoding
+
+	token, err := Encode(secret, Cursor{Order: "search", Page: 1, Site: 7, Visits: 10})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+
+	// A body the server never signed, with a valid signature of another body
+	forged := enc.EncodeToString([]byte(`{"o":"search","p":1,"s":7,"v":1}`)) + "." + sig
+
+	// Signed bodies that are not valid cursors
+	signed := func(body string) string {
+		return enc.EncodeToString([]byte(body)) + "." + enc.EncodeToString(sign(secret, []byte(body)))
+	}
+
+	tampered := []byte(sig)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name  string
+		token string
+		order string
+	}{
+		{name: "empty", token: "", order: "search"},
+		{name: "no signature", token: payload, order: "search"},
+		{name: "tampered signature", token: payload + "." + string(tampered), order: "search"},
+		{name: "tampered body", token: forged, order: "search"},
+		{name: "other secret", token: mustEncode(t, "other", Cursor{Order: "search", Page: 1}), order: "search"},
+		{name: "bad body base64", token: "!!!." + sig, order: "search"},
+		{name: "bad signature base64", token: payload + ".!!!", order: "search"},
+		{name: "bad json", token: signed(`{"o":`), order: "search"},
+		{name: "wrong json type", token: signed(`{"o":"search","p":"1"}`), order: "search"},
+		{name: "other order", token: token, order: "home:newest"},
+		{name: "no page", token: signed(`{"o":"search","p":0}`), order: "search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(secret, tt.token, tt.order); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode() error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func mustEncode(t *testing.T, secret string, c Cursor) string {
+	t.Helper()
+	token, err := Encode(secret, c)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return token
+}
+
+type row struct {
+	site   int64
+	visits int64
+}
+
+// mostVisitedAfter returns a page of rows after c, or the first one, the way
+// the most visited queries do: ordered by (visits DESC, site ASC), keeping
+// rows with fewer visits, or as many visits and a greater site
+func mostVisitedAfter(rows []row, c *Cursor, limit int) []row {
+	sorted := slices.Clone(rows)
+	slices.SortFunc(sorted, func(a, b row) int {
+		return cmp.Or(cmp.Compare(b.visits, a.visits), cmp.Compare(a.site, b.site))
+	})
+
+	var page []row
+	for _, r := range sorted {
+		if c != nil && r.visits >= c.Visits && (r.visits != c.Visits || r.site <= c.Site) {
+			continue
+		}
+		if page = append(page, r); len(page) == limit {
+			break
+		}
+	}
+	return page
+}
+
+func TestKeysetPagesWithEqualVisits(t *testing.T) {
+	const (
+		order = "search"
+		limit = 3
+	)
+
+	// Pages break in the middle of runs of sites with the same visits
+	var rows []row
+	for site := int64(1); site <= 20; site++ {
+		rows = append(rows, row{site: site, visits: []int64{5, 5, 5, 5, 2, 9, 9, 0}[site%8]})
+	}
+
+	var (
+		seen  []row
+		after *Cursor
+	)
+
+	for page := 1; ; page++ {
+		got := mostVisitedAfter(rows, after, limit)
+		seen = append(seen, got...)
+		if len(got) < limit {
+			break
+		}
+
+		last := got[len(got)-1]
+		token := mustEncode(t, secret, Cursor{Order: order, Page: page, Site: last.site, Visits: last.visits})
+
+		c, err := Decode(secret, token, order)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		after = &c
+	}
+
+	want := mostVisitedAfter(rows, nil, len(rows))
+	if !slices.Equal(seen, want) {
+		t.Errorf("paged through %v, want %v", seen, want)
+	}
+}
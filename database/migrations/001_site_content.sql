@@ -0,0 +1,14 @@
+-- Keeps the editor content of published sites next to their rendered HTML,
+-- sites published before are rendered again when next published
+BEGIN;
+
+DROP VIEW sites_with_metrics;
+
+ALTER TABLE sites ADD COLUMN site_content_gz BYTEA NOT NULL DEFAULT '';
+
+CREATE VIEW sites_with_metrics AS
+SELECT s.*, m.*
+FROM sites AS s INNER JOIN site_metrics AS m
+ON s.site_id = m.metric_site;
+
+COMMIT;
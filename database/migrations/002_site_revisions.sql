@@ -0,0 +1,17 @@
+-- Adds the snapshots of every published version of a site
+BEGIN;
+
+CREATE TABLE site_revisions (
+  revision_id BIGSERIAL PRIMARY KEY,
+  revision_site BIGINT NOT NULL,
+  revision_title VARCHAR(63) NOT NULL,
+  revision_description VARCHAR(255) NOT NULL,
+  revision_html_gz BYTEA NOT NULL,
+  revision_content_gz BYTEA NOT NULL,
+  revision_created_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_revisions_site FOREIGN KEY (revision_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_site_revisions_site ON site_revisions(revision_site, revision_id);
+
+COMMIT;
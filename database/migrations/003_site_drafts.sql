@@ -0,0 +1,13 @@
+-- Adds the unpublished editor changes of sites
+BEGIN;
+
+CREATE TABLE site_drafts (
+  draft_site BIGINT PRIMARY KEY,
+  draft_title VARCHAR(63) NOT NULL,
+  draft_description VARCHAR(255) NOT NULL,
+  draft_content_gz BYTEA NOT NULL,
+  draft_modified_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_drafts_site FOREIGN KEY (draft_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+COMMIT;
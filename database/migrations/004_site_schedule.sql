@@ -0,0 +1,17 @@
+-- Adds the times sites are scheduled to be published and unpublished at
+BEGIN;
+
+DROP VIEW sites_with_metrics;
+
+ALTER TABLE sites ADD COLUMN site_publish_at_unix BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN site_unpublish_at_unix BIGINT NOT NULL DEFAULT 0;
+
+CREATE VIEW sites_with_metrics AS
+SELECT s.*, m.*
+FROM sites AS s INNER JOIN site_metrics AS m
+ON s.site_id = m.metric_site;
+
+CREATE INDEX idx_sites_publish_at ON sites(site_publish_at_unix) WHERE site_publish_at_unix > 0;
+CREATE INDEX idx_sites_unpublish_at ON sites(site_unpublish_at_unix) WHERE site_unpublish_at_unix > 0;
+
+COMMIT;
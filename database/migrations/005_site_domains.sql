@@ -0,0 +1,19 @@
+-- Adds the custom domains of sites
+BEGIN;
+
+CREATE TABLE site_domains (
+  domain_id BIGSERIAL PRIMARY KEY,
+  domain_site BIGINT NOT NULL,
+  domain_name VARCHAR(253) NOT NULL,
+  domain_token VARCHAR(63) NOT NULL,
+  domain_verified BIGINT NOT NULL DEFAULT 0,
+  domain_created_unix BIGINT NOT NULL,
+  domain_verified_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_domains_site FOREIGN KEY (domain_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT uq_site_domains_name UNIQUE (domain_name),
+  CONSTRAINT ck_site_domains_verified CHECK (domain_verified IN (0,1))
+);
+
+CREATE INDEX idx_site_domains_site ON site_domains(domain_site);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Adds the extra pages of sites
+BEGIN;
+
+CREATE TABLE site_pages (
+  page_id BIGSERIAL PRIMARY KEY,
+  page_site BIGINT NOT NULL,
+  page_slug VARCHAR(63) NOT NULL,
+  page_title VARCHAR(63) NOT NULL,
+  page_html_gz BYTEA NOT NULL,
+  page_content_gz BYTEA NOT NULL,
+  page_position BIGINT NOT NULL DEFAULT 0,
+  page_published BIGINT NOT NULL DEFAULT 0,
+  page_created_unix BIGINT NOT NULL,
+  page_modified_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_pages_site FOREIGN KEY (page_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT uq_site_pages_slug UNIQUE (page_site, page_slug),
+  CONSTRAINT ck_site_pages_published CHECK (page_published IN (0,1))
+);
+
+CREATE INDEX idx_site_pages_site_position ON site_pages(page_site, page_position);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Adds the daily visit counts of sites, they start counting from now on
+BEGIN;
+
+CREATE TABLE site_visits_daily (
+  daily_site BIGINT NOT NULL,
+  daily_day_unix BIGINT NOT NULL,
+  daily_visits BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_visits_daily PRIMARY KEY (daily_site, daily_day_unix),
+  CONSTRAINT fk_site_visits_daily_site FOREIGN KEY (daily_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+COMMIT;
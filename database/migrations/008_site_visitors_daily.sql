@@ -0,0 +1,17 @@
+-- Adds the daily unique visitors of sites and the salted visitor hashes used
+-- to deduplicate them
+BEGIN;
+
+ALTER TABLE site_visits_daily ADD COLUMN daily_uniques BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE site_visitors_daily (
+  visitor_site BIGINT NOT NULL,
+  visitor_day_unix BIGINT NOT NULL,
+  visitor_hash VARCHAR(32) NOT NULL,
+  CONSTRAINT pk_site_visitors_daily PRIMARY KEY (visitor_site, visitor_day_unix, visitor_hash),
+  CONSTRAINT fk_site_visitors_daily_site FOREIGN KEY (visitor_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_site_visitors_daily_day ON site_visitors_daily(visitor_day_unix);
+
+COMMIT;
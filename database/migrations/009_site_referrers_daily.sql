@@ -0,0 +1,16 @@
+-- Adds the daily referrers and utm campaigns of site visits
+BEGIN;
+
+CREATE TABLE site_referrers_daily (
+  referrer_site BIGINT NOT NULL,
+  referrer_day_unix BIGINT NOT NULL,
+  referrer_host VARCHAR(253) NOT NULL,
+  referrer_utm_source VARCHAR(63) NOT NULL,
+  referrer_utm_medium VARCHAR(63) NOT NULL,
+  referrer_utm_campaign VARCHAR(63) NOT NULL,
+  referrer_visits BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_referrers_daily PRIMARY KEY (referrer_site, referrer_day_unix, referrer_host, referrer_utm_source, referrer_utm_medium, referrer_utm_campaign),
+  CONSTRAINT fk_site_referrers_daily_site FOREIGN KEY (referrer_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+COMMIT;
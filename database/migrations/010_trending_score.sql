@@ -0,0 +1,15 @@
+-- Adds the trending score of sites, the trending job fills it in
+BEGIN;
+
+DROP VIEW sites_with_metrics;
+
+ALTER TABLE site_metrics ADD COLUMN metric_trending_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE VIEW sites_with_metrics AS
+SELECT s.*, m.*
+FROM sites AS s INNER JOIN site_metrics AS m
+ON s.site_id = m.metric_site;
+
+CREATE INDEX idx_site_metrics_trending_score ON site_metrics(metric_trending_score);
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Adds the full text search documents of sites, the search index job fills
+-- them in for sites published before
+BEGIN;
+
+CREATE TABLE site_search (
+  search_site BIGINT PRIMARY KEY,
+  search_title TEXT NOT NULL,
+  search_description TEXT NOT NULL,
+  search_tags TEXT NOT NULL,
+  search_body TEXT NOT NULL,
+  search_es TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', search_title), 'A') ||
+    setweight(to_tsvector('spanish', search_tags), 'A') ||
+    setweight(to_tsvector('spanish', search_description), 'B') ||
+    setweight(to_tsvector('spanish', search_body), 'C')
+  ) STORED,
+  search_en TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', search_title), 'A') ||
+    setweight(to_tsvector('english', search_tags), 'A') ||
+    setweight(to_tsvector('english', search_description), 'B') ||
+    setweight(to_tsvector('english', search_body), 'C')
+  ) STORED,
+  CONSTRAINT fk_site_search_site FOREIGN KEY (search_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_site_search_es ON site_search USING GIN (search_es);
+CREATE INDEX idx_site_search_en ON site_search USING GIN (search_en);
+
+COMMIT;
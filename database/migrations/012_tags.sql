@@ -0,0 +1,66 @@
+-- Moves site tags from sites.site_tags_json into the tags and site_tags
+-- tables. schema.sql already includes this change for new installs, run this
+-- once on databases created before it.
+BEGIN;
+
+CREATE TABLE tags (
+  tag_id BIGSERIAL PRIMARY KEY,
+  tag_name VARCHAR(24) NOT NULL,
+  tag_created_unix BIGINT NOT NULL,
+  CONSTRAINT uq_tags_name UNIQUE (tag_name),
+  CONSTRAINT ck_tags_name CHECK (tag_name <> '' AND tag_name = lower(tag_name))
+);
+
+CREATE TABLE site_tags (
+  site_tag_site BIGINT NOT NULL,
+  site_tag_tag BIGINT NOT NULL,
+  site_tag_color VARCHAR(15) NOT NULL,
+  site_tag_position BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_tags PRIMARY KEY (site_tag_site, site_tag_tag),
+  CONSTRAINT fk_site_tags_site FOREIGN KEY (site_tag_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_tags_tag FOREIGN KEY (site_tag_tag) REFERENCES tags(tag_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_site_tags_tag ON site_tags(site_tag_tag);
+
+CREATE TEMPORARY TABLE legacy_tags ON COMMIT DROP AS
+SELECT DISTINCT ON (s.site_id, lower(btrim(t.tag->>'name')))
+  s.site_id AS site,
+  left(lower(btrim(t.tag->>'name')), 24) AS name,
+  CASE
+    WHEN t.tag->>'color' IN ('blue', 'purple', 'cyan', 'green', 'yellow', 'orange', 'red')
+    THEN t.tag->>'color'
+    ELSE 'blue'
+  END AS color,
+  t.position - 1 AS position
+FROM sites AS s,
+  jsonb_array_elements(COALESCE(NULLIF(s.site_tags_json, ''), '[]')::jsonb)
+  WITH ORDINALITY AS t(tag, position)
+WHERE btrim(COALESCE(t.tag->>'name', '')) <> ''
+ORDER BY s.site_id, lower(btrim(t.tag->>'name')), t.position;
+
+INSERT INTO tags (tag_name, tag_created_unix)
+SELECT DISTINCT name, EXTRACT(EPOCH FROM now())::bigint FROM legacy_tags;
+
+INSERT INTO site_tags (site_tag_site, site_tag_tag, site_tag_color, site_tag_position)
+SELECT l.site, t.tag_id, l.color, l.position
+FROM legacy_tags AS l INNER JOIN tags AS t ON t.tag_name = l.name
+ON CONFLICT DO NOTHING;
+
+DROP VIEW sites_with_metrics;
+
+ALTER TABLE sites DROP COLUMN site_tags_json;
+
+CREATE VIEW sites_with_metrics AS
+SELECT s.*, m.*, COALESCE((
+  SELECT json_agg(
+    json_build_object('name', t.tag_name, 'color', st.site_tag_color)
+    ORDER BY st.site_tag_position
+  )
+  FROM site_tags AS st INNER JOIN tags AS t ON t.tag_id = st.site_tag_tag
+  WHERE st.site_tag_site = s.site_id
+)::text, '[]')::text AS site_tags_json
+FROM sites AS s INNER JOIN site_metrics AS m
+ON s.site_id = m.metric_site;
+
+COMMIT;
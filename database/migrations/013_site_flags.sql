@@ -0,0 +1,17 @@
+-- Adds the review queue of sites flagged by the moderation wordlists
+BEGIN;
+
+CREATE TABLE site_flags (
+  flag_id BIGSERIAL PRIMARY KEY,
+  flag_site BIGINT NOT NULL,
+  flag_field VARCHAR(15) NOT NULL,
+  flag_term VARCHAR(63) NOT NULL,
+  flag_lang VARCHAR(7) NOT NULL,
+  flag_created_unix BIGINT NOT NULL,
+  flag_resolved_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_flags_site FOREIGN KEY (flag_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_site_flags_open ON site_flags(flag_site, flag_field, flag_term) WHERE flag_resolved_unix = 0;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Adds abuse reports from visitors and moderator takedowns of sites
+BEGIN;
+
+CREATE TABLE site_reports (
+  report_id BIGSERIAL PRIMARY KEY,
+  report_site BIGINT NOT NULL,
+  report_reason VARCHAR(15) NOT NULL,
+  report_details VARCHAR(1023) NOT NULL,
+  report_email VARCHAR(63) NOT NULL DEFAULT '',
+  report_ip_hash VARCHAR(32) NOT NULL,
+  report_created_unix BIGINT NOT NULL,
+  report_resolved_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_reports_site FOREIGN KEY (report_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE TABLE site_takedowns (
+  takedown_id BIGSERIAL PRIMARY KEY,
+  takedown_site BIGINT NOT NULL,
+  takedown_moderator BIGINT NOT NULL,
+  takedown_reason VARCHAR(1023) NOT NULL,
+  takedown_created_unix BIGINT NOT NULL,
+  takedown_lifted_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_takedowns_site FOREIGN KEY (takedown_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_takedowns_moderator FOREIGN KEY (takedown_moderator) REFERENCES users(user_id)
+);
+
+CREATE INDEX idx_site_reports_open ON site_reports(report_site) WHERE report_resolved_unix = 0;
+CREATE INDEX idx_site_takedowns_site ON site_takedowns(takedown_site) WHERE takedown_lifted_unix = 0;
+
+COMMIT;
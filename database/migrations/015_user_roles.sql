@@ -0,0 +1,7 @@
+-- Adds the role of users, admins are bootstrapped from CONEX_ADMINS on start
+BEGIN;
+
+ALTER TABLE users ADD COLUMN user_role VARCHAR(15) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT ck_users_role CHECK (user_role IN ('user','moderator','admin'));
+
+COMMIT;
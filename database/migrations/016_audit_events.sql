@@ -0,0 +1,29 @@
+-- Adds the append only log of security relevant actions
+BEGIN;
+
+CREATE TABLE audit_events (
+  event_id BIGSERIAL PRIMARY KEY,
+  event_actor BIGINT NOT NULL,
+  event_action VARCHAR(31) NOT NULL,
+  event_target_type VARCHAR(15) NOT NULL,
+  event_target BIGINT NOT NULL,
+  event_ip VARCHAR(63) NOT NULL,
+  event_user_agent VARCHAR(255) NOT NULL,
+  event_payload JSONB NOT NULL DEFAULT '{}',
+  event_created_unix BIGINT NOT NULL
+);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_audit_events_append_only
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE INDEX idx_audit_events_actor ON audit_events(event_actor, event_id);
+CREATE INDEX idx_audit_events_target ON audit_events(event_target_type, event_target, event_id);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Lets many sites claim a domain until one of them verifies it, so a pending
+-- claim can no longer block the real owner of the domain
+BEGIN;
+
+ALTER TABLE site_domains DROP CONSTRAINT uq_site_domains_name;
+ALTER TABLE site_domains ADD CONSTRAINT uq_site_domains_site_name UNIQUE (domain_site, domain_name);
+
+CREATE UNIQUE INDEX uq_site_domains_verified_name ON site_domains(domain_name) WHERE domain_verified = 1;
+CREATE INDEX idx_site_domains_pending ON site_domains(domain_created_unix) WHERE domain_verified = 0;
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- Pages trending sites by a rank materialized with the scores, so pages do
+-- not shift as every score decays between runs
+BEGIN;
+
+DROP VIEW sites_with_metrics;
+
+ALTER TABLE site_metrics ADD COLUMN metric_trending_rank BIGINT NOT NULL DEFAULT 9223372036854775807;
+
+DROP INDEX idx_site_metrics_trending_score;
+CREATE INDEX idx_site_metrics_trending_rank ON site_metrics(metric_trending_rank, metric_site);
+
+-- Ranks the current scores, the trending job keeps them up to date
+UPDATE site_metrics AS m SET
+metric_trending_rank = ranked.rank
+FROM (
+  SELECT metric_site, row_number() OVER (
+    ORDER BY metric_trending_score DESC, metric_visits_total DESC, metric_site ASC
+  ) AS rank
+  FROM site_metrics
+) AS ranked
+WHERE m.metric_site = ranked.metric_site;
+
+CREATE VIEW sites_with_metrics AS
+SELECT s.*, m.*, COALESCE((
+  SELECT json_agg(
+    json_build_object('name', t.tag_name, 'color', st.site_tag_color)
+    ORDER BY st.site_tag_position
+  )
+  FROM site_tags AS st INNER JOIN tags AS t ON t.tag_id = st.site_tag_tag
+  WHERE st.site_tag_site = s.site_id
+)::text, '[]')::text AS site_tags_json
+FROM sites AS s INNER JOIN site_metrics AS m
+ON s.site_id = m.metric_site;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Records whether a site was published when it was taken down, so lifting the
+-- takedown does not publish sites their owners had unpublished. Takedowns made
+-- before are assumed published, as lifting them always published the site
+BEGIN;
+
+ALTER TABLE site_takedowns ADD COLUMN takedown_site_published BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE site_takedowns ALTER COLUMN takedown_site_published SET DEFAULT 0;
+
+COMMIT;
@@ -49,6 +49,26 @@ UPDATE site_sync SET
   site_sync_last_update_unix = $2
 WHERE site_sync_id = $3;
 
+-- name: GetDraft :one
+SELECT * FROM site_drafts WHERE draft_site = $1;
+
+-- name: UpsertDraft :exec
+INSERT INTO site_drafts (
+  draft_site,
+  draft_title,
+  draft_description,
+  draft_content_gz,
+  draft_modified_unix
+) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (draft_site) DO UPDATE SET
+  draft_title = EXCLUDED.draft_title,
+  draft_description = EXCLUDED.draft_description,
+  draft_content_gz = EXCLUDED.draft_content_gz,
+  draft_modified_unix = EXCLUDED.draft_modified_unix;
+
+-- name: DeleteDraft :exec
+DELETE FROM site_drafts WHERE draft_site = $1;
+
 -- name: GetMetricsBySiteID :one
 SELECT * FROM site_metrics WHERE metric_site = $1;
 
@@ -67,35 +87,52 @@ WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
 ORDER BY metric_visits_total DESC, site_id ASC
 LIMIT 30;
 
--- name: GetValidSitesWithMetricsFromMostTotalVisitsLessThan :many
-SELECT *
-FROM sites_with_metrics
-WHERE site_published = 1
-  AND site_deleted = 0
+-- name: GetHomePageSitesWithMetricsFromMostTrending :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+ORDER BY metric_trending_rank ASC, site_id ASC
+LIMIT 30;
+
+-- name: GetHomePageSitesWithMetricsFromNewest :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+ORDER BY site_created_unix DESC, site_id DESC
+LIMIT 30;
+
+-- name: GetHomePageSitesWithMetricsFromMostTrendingAfter :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+  AND (metric_trending_rank, site_id) > (sqlc.arg(rank)::bigint, sqlc.arg(site)::bigint)
+ORDER BY metric_trending_rank ASC, site_id ASC
+LIMIT 30;
+
+-- name: GetHomePageSitesWithMetricsFromNewestOlderThan :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+  AND (site_created_unix, site_id) < (sqlc.arg(created)::bigint, sqlc.arg(site)::bigint)
+ORDER BY site_created_unix DESC, site_id DESC
+LIMIT 30;
+
+-- name: GetHomePageSitesWithMetricsFromMostTotalVisitsLessThan :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
   AND (
-    metric_visits_total < $1
-    OR (metric_visits_total = $2 AND site_id > $3)
+    metric_visits_total < sqlc.arg(visits)::bigint
+    OR (metric_visits_total = sqlc.arg(visits)::bigint AND site_id > sqlc.arg(site)::bigint)
   )
 ORDER BY metric_visits_total DESC, site_id ASC
 LIMIT 30;
 
--- name: GetValidSitesWithMetricsFromLeastTotalVisits :many
-SELECT *
-FROM sites_with_metrics
-WHERE site_published = 1 AND site_deleted = 0
-ORDER BY metric_visits_total ASC, site_id ASC
-LIMIT 30;
-
--- name: GetValidSitesWithMetricsFromLeastTotalVisitsMoreThan :many
+-- name: GetValidSitesWithMetricsFromMostTotalVisitsLessThan :many
 SELECT *
 FROM sites_with_metrics
 WHERE site_published = 1
   AND site_deleted = 0
   AND (
-    metric_visits_total > $1
+    metric_visits_total < $1
     OR (metric_visits_total = $2 AND site_id > $3)
   )
-ORDER BY metric_visits_total ASC, site_id ASC
+ORDER BY metric_visits_total DESC, site_id ASC
 LIMIT 30;
 
 -- name: InsertUser :one
@@ -111,6 +148,25 @@ UPDATE users SET
   user_modified_unix = $2
 WHERE user_id = $3;
 
+-- name: UpdateUserRole :exec
+UPDATE users SET
+  user_role = $1,
+  user_modified_unix = $2
+WHERE user_id = $3;
+
+-- name: PromoteAdmins :execrows
+UPDATE users SET user_role = 'admin'
+WHERE user_deleted = 0
+  AND user_role <> 'admin'
+  AND lower(user_email) = ANY(sqlc.arg(emails)::text[]);
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE user_deleted = 0
+  AND user_email ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY user_id DESC
+LIMIT sqlc.arg(lim);
+
 -- name: UserExists :one
 SELECT EXISTS (
   SELECT 1
@@ -179,7 +235,6 @@ INSERT INTO sites (
   site_user,
   site_slug,
   site_title,
-  site_tags_json,
   site_description,
   site_html_gz,
   site_created_unix,
@@ -188,15 +243,15 @@ INSERT INTO sites (
   site_home_page,
   site_deleted
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 ) RETURNING site_id;
 
 -- name: UpdateSite :exec
 UPDATE sites SET
   site_title = $1,
   site_description = $2,
-  site_tags_json = $3,
-  site_html_gz = $4,
+  site_html_gz = $3,
+  site_content_gz = $4,
   site_modified_unix = $5,
   site_published = $6,
   site_deleted = $7
@@ -207,6 +262,38 @@ UPDATE sites SET
   site_published = 1
 WHERE site_id = $1;
 
+-- name: UpdateSiteSchedule :exec
+UPDATE sites SET
+  site_publish_at_unix = $1,
+  site_unpublish_at_unix = $2
+WHERE site_id = $3;
+
+-- name: GetSitesDueToPublish :many
+SELECT site_id FROM sites
+WHERE site_publish_at_unix > 0
+  AND site_publish_at_unix <= $1
+  AND site_deleted = 0
+  AND NOT EXISTS (
+    SELECT 1 FROM site_takedowns
+    WHERE takedown_site = site_id AND takedown_lifted_unix = 0
+  );
+
+-- name: GetSitesDueToUnpublish :many
+SELECT site_id FROM sites
+WHERE site_unpublish_at_unix > 0
+  AND site_unpublish_at_unix <= $1
+  AND site_deleted = 0;
+
+-- name: ClearSitePublishAt :exec
+UPDATE sites SET
+  site_publish_at_unix = 0
+WHERE site_id = $1;
+
+-- name: ClearSiteUnpublishAt :exec
+UPDATE sites SET
+  site_unpublish_at_unix = 0
+WHERE site_id = $1;
+
 -- name: InsertObject :one
 INSERT INTO site_objects(
   object_site,
@@ -257,9 +344,8 @@ INSERT INTO site_metrics (
 -- name: UpdateSiteSettings :exec
 UPDATE sites SET
   site_modified_unix = $1,
-  site_home_page = $2,
-  site_tags_json = $3
-WHERE site_id = $4;
+  site_home_page = $2
+WHERE site_id = $3;
 
 -- name: DeleteObject :exec
 DELETE FROM site_objects WHERE object_bucket = $1 AND object_key = $2;
@@ -290,6 +376,16 @@ INSERT INTO payments (
   payment_reference
 ) VALUES ($1, $2, $3, $4, $5) RETURNING payment_id;
 
+-- name: GetPaymentsByUser :many
+SELECT * FROM payments WHERE payment_user = $1
+ORDER BY payment_date_unix DESC, payment_id DESC;
+
+-- name: GetRecentPayments :many
+SELECT p.*, u.user_email FROM payments AS p
+INNER JOIN users AS u ON u.user_id = p.payment_user
+ORDER BY p.payment_date_unix DESC, p.payment_id DESC
+LIMIT $1;
+
 -- name: DeleteUser :exec
 UPDATE users SET
   user_email = '',
@@ -300,7 +396,598 @@ WHERE user_id = $2;
 -- name: DeleteSite :exec
 DELETE FROM sites WHERE site_id = $1;
 
--- name: NewVisit :exec
+-- name: AddVisits :exec
 UPDATE site_metrics SET
-metric_visits_total = metric_visits_total + 1
-WHERE metric_site = $1;
+metric_visits_total = metric_visits_total + v.visits
+FROM (
+  SELECT
+    unnest(sqlc.arg(sites)::bigint[]) AS site,
+    unnest(sqlc.arg(visits)::bigint[]) AS visits
+) AS v
+WHERE metric_site = v.site;
+
+-- name: AddDailyVisits :exec
+INSERT INTO site_visits_daily (
+  daily_site,
+  daily_day_unix,
+  daily_visits
+)
+SELECT v.site, sqlc.arg(day_unix)::bigint, v.visits
+FROM (
+  SELECT
+    unnest(sqlc.arg(sites)::bigint[]) AS site,
+    unnest(sqlc.arg(visits)::bigint[]) AS visits
+) AS v
+WHERE EXISTS (SELECT 1 FROM sites WHERE site_id = v.site)
+ON CONFLICT (daily_site, daily_day_unix) DO UPDATE SET
+daily_visits = site_visits_daily.daily_visits + EXCLUDED.daily_visits;
+
+-- name: AddDailyVisitors :exec
+WITH inserted AS (
+  INSERT INTO site_visitors_daily (
+    visitor_site,
+    visitor_day_unix,
+    visitor_hash
+  )
+  SELECT v.site, v.day, v.hash
+  FROM (
+    SELECT
+      unnest(sqlc.arg(sites)::bigint[]) AS site,
+      unnest(sqlc.arg(days)::bigint[]) AS day,
+      unnest(sqlc.arg(hashes)::text[]) AS hash
+  ) AS v
+  WHERE EXISTS (SELECT 1 FROM sites WHERE site_id = v.site)
+  ON CONFLICT DO NOTHING
+  RETURNING visitor_site, visitor_day_unix
+)
+INSERT INTO site_visits_daily (
+  daily_site,
+  daily_day_unix,
+  daily_uniques
+)
+SELECT visitor_site, visitor_day_unix, COUNT(*)
+FROM inserted
+GROUP BY visitor_site, visitor_day_unix
+ON CONFLICT (daily_site, daily_day_unix) DO UPDATE SET
+daily_uniques = site_visits_daily.daily_uniques + EXCLUDED.daily_uniques;
+
+-- name: AddDailyReferrers :exec
+INSERT INTO site_referrers_daily (
+  referrer_site,
+  referrer_day_unix,
+  referrer_host,
+  referrer_utm_source,
+  referrer_utm_medium,
+  referrer_utm_campaign,
+  referrer_visits
+)
+SELECT v.site, v.day, v.host, v.utm_source, v.utm_medium, v.utm_campaign, v.visits
+FROM (
+  SELECT
+    unnest(sqlc.arg(sites)::bigint[]) AS site,
+    unnest(sqlc.arg(days)::bigint[]) AS day,
+    unnest(sqlc.arg(hosts)::text[]) AS host,
+    unnest(sqlc.arg(utm_sources)::text[]) AS utm_source,
+    unnest(sqlc.arg(utm_mediums)::text[]) AS utm_medium,
+    unnest(sqlc.arg(utm_campaigns)::text[]) AS utm_campaign,
+    unnest(sqlc.arg(visits)::bigint[]) AS visits
+) AS v
+WHERE EXISTS (SELECT 1 FROM sites WHERE site_id = v.site)
+ON CONFLICT (referrer_site, referrer_day_unix, referrer_host, referrer_utm_source, referrer_utm_medium, referrer_utm_campaign) DO UPDATE SET
+referrer_visits = site_referrers_daily.referrer_visits + EXCLUDED.referrer_visits;
+
+-- name: GetTopReferrersByUser :many
+SELECT referrer_site, referrer_name, referrer_visits FROM (
+  SELECT
+    r.referrer_site,
+    COALESCE(NULLIF(r.referrer_host, ''), r.referrer_utm_source)::text AS referrer_name,
+    SUM(r.referrer_visits)::bigint AS referrer_visits,
+    ROW_NUMBER() OVER (
+      PARTITION BY r.referrer_site
+      ORDER BY SUM(r.referrer_visits) DESC
+    ) AS referrer_rank
+  FROM site_referrers_daily AS r
+  INNER JOIN sites AS s ON s.site_id = r.referrer_site
+  WHERE s.site_user = sqlc.arg(site_user)
+    AND r.referrer_day_unix >= sqlc.arg(since_unix)
+    AND (r.referrer_host <> '' OR r.referrer_utm_source <> '')
+  GROUP BY r.referrer_site, 2
+) AS top
+WHERE referrer_rank <= sqlc.arg(per_site)::bigint
+ORDER BY referrer_site, referrer_visits DESC;
+
+-- name: GetDailyReferrers :many
+SELECT * FROM site_referrers_daily
+WHERE referrer_site = $1 AND referrer_day_unix >= sqlc.arg(since_unix)
+ORDER BY referrer_day_unix, referrer_visits DESC;
+
+-- name: UpdateTrendingScores :exec
+UPDATE site_metrics AS m SET
+metric_trending_score = COALESCE((
+  SELECT SUM(
+    d.daily_uniques * power(
+      0.5,
+      (sqlc.arg(now_unix)::bigint - d.daily_day_unix) / 86400.0 / sqlc.arg(half_life_days)::float8
+    )
+  )
+  FROM site_visits_daily AS d
+  WHERE d.daily_site = m.metric_site AND d.daily_day_unix >= sqlc.arg(since_unix)
+), 0)
+WHERE m.metric_trending_score <> 0 OR EXISTS (
+  SELECT 1 FROM site_visits_daily AS d
+  WHERE d.daily_site = m.metric_site AND d.daily_day_unix >= sqlc.arg(since_unix)
+);
+
+-- name: RankTrendingSites :exec
+UPDATE site_metrics AS m SET
+metric_trending_rank = ranked.rank
+FROM (
+  SELECT metric_site, row_number() OVER (
+    ORDER BY metric_trending_score DESC, metric_visits_total DESC, metric_site ASC
+  ) AS rank
+  FROM site_metrics
+) AS ranked
+WHERE m.metric_site = ranked.metric_site AND m.metric_trending_rank <> ranked.rank;
+
+-- name: DeleteVisitorsBefore :exec
+DELETE FROM site_visitors_daily WHERE visitor_day_unix < sqlc.arg(day_unix);
+
+-- name: GetDailyVisits :many
+SELECT
+  d.daily_day_unix,
+  SUM(d.daily_visits)::bigint AS daily_visits,
+  SUM(d.daily_uniques)::bigint AS daily_uniques
+FROM site_visits_daily AS d
+INNER JOIN sites AS s ON s.site_id = d.daily_site
+WHERE s.site_user = sqlc.arg(site_user)
+  AND (sqlc.arg(site_id)::bigint = 0 OR d.daily_site = sqlc.arg(site_id))
+  AND d.daily_day_unix >= sqlc.arg(since_unix)
+GROUP BY d.daily_day_unix
+ORDER BY d.daily_day_unix;
+
+-- name: InsertRevision :one
+INSERT INTO site_revisions (
+  revision_site,
+  revision_title,
+  revision_description,
+  revision_html_gz,
+  revision_content_gz,
+  revision_created_unix
+) VALUES ($1, $2, $3, $4, $5, $6) RETURNING revision_id;
+
+-- name: GetRevisionsBySite :many
+SELECT
+  revision_id,
+  revision_site,
+  revision_title,
+  revision_description,
+  revision_created_unix
+FROM site_revisions
+WHERE revision_site = $1
+ORDER BY revision_id DESC;
+
+-- name: GetRevision :one
+SELECT * FROM site_revisions
+WHERE revision_id = $1 AND revision_site = $2;
+
+-- name: PruneRevisions :exec
+DELETE FROM site_revisions
+WHERE revision_site = sqlc.arg(site_id)::BIGINT
+  AND revision_id < COALESCE((
+    SELECT kept.revision_id FROM site_revisions AS kept
+    WHERE kept.revision_site = sqlc.arg(site_id)::BIGINT
+    ORDER BY kept.revision_id DESC
+    OFFSET sqlc.arg(keep)::BIGINT - 1
+    LIMIT 1
+  ), 0);
+
+-- name: InsertDomain :one
+INSERT INTO site_domains (
+  domain_site,
+  domain_name,
+  domain_token,
+  domain_created_unix
+) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetDomainsBySite :many
+SELECT * FROM site_domains
+WHERE domain_site = $1
+ORDER BY domain_id;
+
+-- name: GetDomain :one
+SELECT * FROM site_domains
+WHERE domain_id = $1 AND domain_site = $2;
+
+-- name: GetSlugByVerifiedDomain :one
+SELECT s.site_slug FROM site_domains AS d
+INNER JOIN sites AS s ON s.site_id = d.domain_site
+INNER JOIN user_plans AS p ON p.user_plan_user = s.site_user
+WHERE d.domain_name = $1
+  AND d.domain_verified = 1
+  AND p.user_plan_active = 1
+  AND p.user_plan_due_unix > $2;
+
+-- name: VerifyDomain :exec
+UPDATE site_domains SET
+  domain_verified = 1,
+  domain_verified_unix = $1
+WHERE domain_id = $2;
+
+-- name: DeleteDomain :exec
+DELETE FROM site_domains
+WHERE domain_id = $1 AND domain_site = $2;
+
+-- name: GetVerifiedDomainSite :one
+SELECT domain_site FROM site_domains
+WHERE domain_name = $1 AND domain_verified = 1;
+
+-- name: DeletePendingDomainsByName :exec
+DELETE FROM site_domains
+WHERE domain_name = $1 AND domain_verified = 0;
+
+-- name: DeletePendingDomainsBefore :exec
+DELETE FROM site_domains
+WHERE domain_verified = 0 AND domain_created_unix < sqlc.arg(created_unix);
+
+-- name: InsertPage :one
+INSERT INTO site_pages (
+  page_site,
+  page_slug,
+  page_title,
+  page_html_gz,
+  page_content_gz,
+  page_position,
+  page_created_unix,
+  page_modified_unix
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING page_id;
+
+-- name: GetPagesBySite :many
+SELECT
+  page_id,
+  page_slug,
+  page_title,
+  page_position,
+  page_published,
+  page_modified_unix
+FROM site_pages
+WHERE page_site = $1
+ORDER BY page_position, page_id;
+
+-- name: GetPublishedPagesBySite :many
+SELECT page_slug, page_title FROM site_pages
+WHERE page_site = $1 AND page_published = 1
+ORDER BY page_position, page_id;
+
+-- name: GetPage :one
+SELECT * FROM site_pages
+WHERE page_site = $1 AND page_slug = $2;
+
+-- name: CountPagesBySite :one
+SELECT COUNT(*) FROM site_pages WHERE page_site = $1;
+
+-- name: GetNextPagePosition :one
+SELECT COALESCE(MAX(page_position) + 1, 0)::bigint FROM site_pages WHERE page_site = $1;
+
+-- name: LockSitePages :exec
+SELECT site_id FROM sites WHERE site_id = $1 FOR UPDATE;
+
+-- name: UpdatePage :exec
+UPDATE site_pages SET
+  page_title = $1,
+  page_html_gz = $2,
+  page_content_gz = $3,
+  page_published = $4,
+  page_modified_unix = $5
+WHERE page_id = $6;
+
+-- name: UpdatePagePosition :exec
+UPDATE site_pages SET
+  page_position = $1
+WHERE page_id = $2;
+
+-- name: DeletePage :exec
+DELETE FROM site_pages
+WHERE page_site = $1 AND page_slug = $2;
+
+-- name: CompactPagePositions :exec
+UPDATE site_pages AS p SET page_position = o.position
+FROM (
+  SELECT s.page_id, row_number() OVER (ORDER BY s.page_position, s.page_id) - 1 AS position
+  FROM site_pages AS s
+  WHERE s.page_site = $1
+) AS o
+WHERE p.page_id = o.page_id AND p.page_position <> o.position;
+
+-- name: GetFeedSites :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+ORDER BY site_modified_unix DESC, site_id DESC
+LIMIT $1;
+
+-- name: GetFeedSitesByTag :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+  AND EXISTS (
+    SELECT 1 FROM site_tags
+    INNER JOIN tags ON tag_id = site_tag_tag
+    WHERE site_tag_site = site_id AND tag_name = lower(sqlc.arg(tag)::text)
+  )
+ORDER BY site_modified_unix DESC, site_id DESC
+LIMIT sqlc.arg(max_items);
+
+-- name: GetFeedSitesByAuthorOf :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_deleted = 0 AND site_user = (
+    SELECT s.site_user FROM sites AS s
+    WHERE s.site_slug = sqlc.arg(slug) AND s.site_published = 1 AND s.site_deleted = 0
+  )
+ORDER BY site_modified_unix DESC, site_id DESC
+LIMIT sqlc.arg(max_items);
+
+-- name: CountSitemapEntries :one
+SELECT
+  ((SELECT COUNT(*) FROM sites
+    WHERE site_published = 1 AND site_deleted = 0)
+  + (SELECT COUNT(*) FROM site_pages
+    INNER JOIN sites ON site_id = page_site
+    WHERE site_published = 1 AND site_deleted = 0 AND page_published = 1))::bigint
+  AS total;
+
+-- name: GetSitemapEntries :many
+SELECT site_slug AS slug, ''::text AS page, site_modified_unix AS modified
+FROM sites
+WHERE site_published = 1 AND site_deleted = 0
+UNION ALL
+SELECT site_slug AS slug, page_slug::text AS page, page_modified_unix AS modified
+FROM site_pages
+INNER JOIN sites ON site_id = page_site
+WHERE site_published = 1 AND site_deleted = 0 AND page_published = 1
+ORDER BY slug, page
+LIMIT $1 OFFSET $2;
+
+-- name: UpsertSiteSearch :exec
+INSERT INTO site_search (
+  search_site,
+  search_title,
+  search_description,
+  search_tags,
+  search_body
+) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (search_site) DO UPDATE SET
+  search_title = EXCLUDED.search_title,
+  search_description = EXCLUDED.search_description,
+  search_tags = EXCLUDED.search_tags,
+  search_body = EXCLUDED.search_body;
+
+-- name: GetSitesMissingSearch :many
+SELECT s.* FROM sites AS s
+LEFT JOIN site_search AS x ON x.search_site = s.site_id
+WHERE x.search_site IS NULL AND s.site_deleted = 0
+ORDER BY s.site_id
+LIMIT $1;
+
+-- name: SearchSitesSpanish :many
+WITH ranked AS (
+  SELECT
+    x.search_site,
+    x.search_description,
+    x.search_body,
+    ts_rank(x.search_es, q)::float8 AS search_rank,
+    q AS search_query
+  FROM site_search AS x, websearch_to_tsquery('spanish', sqlc.arg(query)::text) AS q
+  WHERE x.search_es @@ q
+)
+SELECT
+  sqlc.embed(w),
+  r.search_rank,
+  ts_headline(
+    'spanish',
+    r.search_description || ' ' || r.search_body,
+    r.search_query,
+    'StartSel=⟦, StopSel=⟧, MaxFragments=2, MaxWords=20, MinWords=8'
+  )::text AS search_headline
+FROM ranked AS r
+INNER JOIN sites_with_metrics AS w ON w.site_id = r.search_site
+WHERE w.site_published = 1 AND w.site_deleted = 0
+  AND (
+    r.search_rank < sqlc.arg(rank)::float8
+    OR (r.search_rank = sqlc.arg(rank)::float8 AND w.site_id > sqlc.arg(site)::bigint)
+  )
+ORDER BY r.search_rank DESC, w.site_id ASC
+LIMIT 30;
+
+-- name: SearchSitesEnglish :many
+WITH ranked AS (
+  SELECT
+    x.search_site,
+    x.search_description,
+    x.search_body,
+    ts_rank(x.search_en, q)::float8 AS search_rank,
+    q AS search_query
+  FROM site_search AS x, websearch_to_tsquery('english', sqlc.arg(query)::text) AS q
+  WHERE x.search_en @@ q
+)
+SELECT
+  sqlc.embed(w),
+  r.search_rank,
+  ts_headline(
+    'english',
+    r.search_description || ' ' || r.search_body,
+    r.search_query,
+    'StartSel=⟦, StopSel=⟧, MaxFragments=2, MaxWords=20, MinWords=8'
+  )::text AS search_headline
+FROM ranked AS r
+INNER JOIN sites_with_metrics AS w ON w.site_id = r.search_site
+WHERE w.site_published = 1 AND w.site_deleted = 0
+  AND (
+    r.search_rank < sqlc.arg(rank)::float8
+    OR (r.search_rank = sqlc.arg(rank)::float8 AND w.site_id > sqlc.arg(site)::bigint)
+  )
+ORDER BY r.search_rank DESC, w.site_id ASC
+LIMIT 30;
+
+-- name: UpsertTag :one
+INSERT INTO tags (tag_name, tag_created_unix)
+VALUES ($1, $2)
+ON CONFLICT (tag_name) DO UPDATE SET tag_name = EXCLUDED.tag_name
+RETURNING tag_id;
+
+-- name: GetTag :one
+SELECT * FROM tags WHERE tag_name = $1;
+
+-- name: GetSiteTags :many
+SELECT tag_id, tag_name, site_tag_color FROM site_tags
+INNER JOIN tags ON tag_id = site_tag_tag
+WHERE site_tag_site = $1
+ORDER BY site_tag_position;
+
+-- name: DeleteSiteTags :exec
+DELETE FROM site_tags WHERE site_tag_site = $1;
+
+-- name: InsertSiteTag :exec
+INSERT INTO site_tags (
+  site_tag_site,
+  site_tag_tag,
+  site_tag_color,
+  site_tag_position
+) VALUES ($1, $2, $3, $4);
+
+-- name: UpdateSiteTagColor :execrows
+UPDATE site_tags SET site_tag_color = $1
+WHERE site_tag_site = $2
+  AND site_tag_tag = (SELECT tag_id FROM tags WHERE tag_name = $3);
+
+-- name: GetTagCloud :many
+SELECT tag_name, COUNT(*)::bigint AS tag_sites FROM tags
+INNER JOIN site_tags ON site_tag_tag = tag_id
+INNER JOIN sites ON site_id = site_tag_site
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+GROUP BY tag_name
+ORDER BY tag_sites DESC, tag_name ASC
+LIMIT $1;
+
+-- name: GetHomePageSitesWithMetricsByTagLessThan :many
+SELECT * FROM sites_with_metrics
+WHERE site_published = 1 AND site_home_page = 1 AND site_deleted = 0
+  AND EXISTS (
+    SELECT 1 FROM site_tags
+    WHERE site_tag_site = site_id AND site_tag_tag = sqlc.arg(tag)::bigint
+  )
+  AND (
+    metric_visits_total < sqlc.arg(visits)::bigint
+    OR (metric_visits_total = sqlc.arg(visits)::bigint AND site_id > sqlc.arg(site)::bigint)
+  )
+ORDER BY metric_visits_total DESC, site_id ASC
+LIMIT 30;
+
+-- name: InsertSiteFlag :exec
+INSERT INTO site_flags (
+  flag_site,
+  flag_field,
+  flag_term,
+  flag_lang,
+  flag_created_unix
+) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (flag_site, flag_field, flag_term) WHERE flag_resolved_unix = 0
+DO NOTHING;
+
+-- name: GetOpenSiteFlags :many
+SELECT f.*, s.site_slug, s.site_title FROM site_flags AS f
+INNER JOIN sites AS s ON s.site_id = f.flag_site
+WHERE f.flag_resolved_unix = 0
+ORDER BY f.flag_created_unix ASC, f.flag_id ASC;
+
+-- name: ResolveSiteFlags :exec
+UPDATE site_flags SET flag_resolved_unix = $1
+WHERE flag_site = $2 AND flag_resolved_unix = 0;
+
+-- name: InsertSiteReport :one
+INSERT INTO site_reports (
+  report_site,
+  report_reason,
+  report_details,
+  report_ip_hash,
+  report_created_unix
+) VALUES ($1, $2, $3, $4, $5)
+RETURNING report_id;
+
+-- name: UpdateSiteReportEmail :exec
+UPDATE site_reports SET report_email = $1
+WHERE report_id = $2;
+
+-- name: GetOpenSiteReports :many
+SELECT r.*, s.site_slug, s.site_title FROM site_reports AS r
+INNER JOIN sites AS s ON s.site_id = r.report_site
+WHERE r.report_resolved_unix = 0
+ORDER BY r.report_created_unix ASC, r.report_id ASC;
+
+-- name: ResolveSiteReport :execrows
+UPDATE site_reports SET report_resolved_unix = $1
+WHERE report_id = $2 AND report_resolved_unix = 0;
+
+-- name: ResolveSiteReports :exec
+UPDATE site_reports SET report_resolved_unix = $1
+WHERE report_site = $2 AND report_resolved_unix = 0;
+
+-- name: TakedownSite :exec
+UPDATE sites SET
+  site_published = 0,
+  site_publish_at_unix = 0,
+  site_unpublish_at_unix = 0,
+  site_modified_unix = $1
+WHERE site_id = $2;
+
+-- name: InsertTakedown :exec
+INSERT INTO site_takedowns (
+  takedown_site,
+  takedown_moderator,
+  takedown_reason,
+  takedown_created_unix,
+  takedown_site_published
+) VALUES ($1, $2, $3, $4, $5);
+
+-- name: CountActiveTakedowns :one
+SELECT COUNT(*) FROM site_takedowns
+WHERE takedown_site = $1 AND takedown_lifted_unix = 0;
+
+-- name: GetActiveTakedownsByUser :many
+SELECT t.*, s.site_slug, s.site_title FROM site_takedowns AS t
+INNER JOIN sites AS s ON s.site_id = t.takedown_site
+WHERE s.site_user = $1 AND s.site_deleted = 0 AND t.takedown_lifted_unix = 0
+ORDER BY t.takedown_created_unix DESC;
+
+-- name: LiftTakedowns :many
+UPDATE site_takedowns SET takedown_lifted_unix = $1
+WHERE takedown_site = $2 AND takedown_lifted_unix = 0
+RETURNING takedown_site_published;
+
+-- name: InsertAuditEvent :exec
+INSERT INTO audit_events (
+  event_actor,
+  event_action,
+  event_target_type,
+  event_target,
+  event_ip,
+  event_user_agent,
+  event_payload,
+  event_created_unix
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetAuditEventsByUser :many
+SELECT * FROM audit_events
+WHERE event_actor = sqlc.arg(user_id)::bigint
+  OR (event_target_type = 'user' AND event_target = sqlc.arg(user_id)::bigint)
+ORDER BY event_id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: GetAuditEvents :many
+SELECT e.*, COALESCE(u.user_email, '')::text AS actor_email
+FROM audit_events AS e
+LEFT JOIN users AS u ON u.user_id = e.event_actor
+WHERE (sqlc.arg(user_id)::bigint = 0
+    OR e.event_actor = sqlc.arg(user_id)::bigint
+    OR (e.event_target_type = 'user' AND e.event_target = sqlc.arg(user_id)::bigint))
+  AND (sqlc.arg(action)::text = '' OR e.event_action = sqlc.arg(action)::text)
+  AND (sqlc.arg(before)::bigint = 0 OR e.event_id < sqlc.arg(before)::bigint)
+ORDER BY e.event_id DESC
+LIMIT sqlc.arg(lim);
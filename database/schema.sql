@@ -6,7 +6,9 @@ CREATE TABLE users (
   user_created_unix BIGINT NOT NULL,
   user_modified_unix BIGINT NOT NULL,
   user_deleted BIGINT NOT NULL DEFAULT 0,
-  CONSTRAINT ck_users_deleted CHECK (user_deleted IN (0,1))
+  user_role VARCHAR(15) NOT NULL DEFAULT 'user',
+  CONSTRAINT ck_users_deleted CHECK (user_deleted IN (0,1)),
+  CONSTRAINT ck_users_role CHECK (user_role IN ('user','moderator','admin'))
 );
 
 CREATE UNIQUE INDEX uq_users_email_active_only
@@ -48,19 +50,41 @@ CREATE TABLE sites (
   site_user BIGINT NOT NULL,
   site_slug VARCHAR(63) NOT NULL,
   site_title VARCHAR(63) NOT NULL,
-  site_tags_json VARCHAR(255) NOT NULL,
   site_description VARCHAR(255) NOT NULL,
   site_html_gz BYTEA NOT NULL,
+  site_content_gz BYTEA NOT NULL DEFAULT '',
   site_created_unix BIGINT NOT NULL,
   site_modified_unix BIGINT NOT NULL,
   site_home_page BIGINT NOT NULL DEFAULT 0,
   site_published BIGINT NOT NULL DEFAULT 1,
   site_deleted BIGINT NOT NULL DEFAULT 0,
+  site_publish_at_unix BIGINT NOT NULL DEFAULT 0,
+  site_unpublish_at_unix BIGINT NOT NULL DEFAULT 0,
   CONSTRAINT fk_sites_user FOREIGN KEY (site_user) REFERENCES users(user_id),
   CONSTRAINT uq_sites_slug UNIQUE (site_slug),
   CONSTRAINT ck_sites_deleted CHECK (site_deleted IN (0,1))
 );
 
+CREATE TABLE tags (
+  tag_id BIGSERIAL PRIMARY KEY,
+  tag_name VARCHAR(24) NOT NULL,
+  tag_created_unix BIGINT NOT NULL,
+  CONSTRAINT uq_tags_name UNIQUE (tag_name),
+  CONSTRAINT ck_tags_name CHECK (tag_name <> '' AND tag_name = lower(tag_name))
+);
+
+-- Tags of a site, colors are picked by the owner of each site from
+-- database.TagColors
+CREATE TABLE site_tags (
+  site_tag_site BIGINT NOT NULL,
+  site_tag_tag BIGINT NOT NULL,
+  site_tag_color VARCHAR(15) NOT NULL,
+  site_tag_position BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_tags PRIMARY KEY (site_tag_site, site_tag_tag),
+  CONSTRAINT fk_site_tags_site FOREIGN KEY (site_tag_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_tags_tag FOREIGN KEY (site_tag_tag) REFERENCES tags(tag_id) ON DELETE CASCADE
+);
+
 CREATE TABLE site_sync (
   site_sync_id BIGINT PRIMARY KEY,
   site_sync_data_gz BYTEA NOT NULL,
@@ -68,15 +92,150 @@ CREATE TABLE site_sync (
   CONSTRAINT fk_site_sync FOREIGN KEY (site_sync_id) REFERENCES sites(site_id) ON DELETE CASCADE
 );
 
+CREATE TABLE site_drafts (
+  draft_site BIGINT PRIMARY KEY,
+  draft_title VARCHAR(63) NOT NULL,
+  draft_description VARCHAR(255) NOT NULL,
+  draft_content_gz BYTEA NOT NULL,
+  draft_modified_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_drafts_site FOREIGN KEY (draft_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
 
 CREATE TABLE site_metrics (
   metric_id BIGSERIAL PRIMARY KEY,
   metric_site BIGINT NOT NULL,
   metric_visits_total BIGINT NOT NULL,
+  metric_trending_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  -- Position in the trending order as of the last ranking, pages of trending
+  -- sites follow it so they do not shift as scores decay. Sites not ranked
+  -- yet sort last
+  metric_trending_rank BIGINT NOT NULL DEFAULT 9223372036854775807,
   CONSTRAINT fk_sites_metrics_site FOREIGN KEY (metric_site) REFERENCES sites(site_id) ON DELETE CASCADE,
   CONSTRAINT uq_site_metrics UNIQUE (metric_site)
 );
 
+CREATE TABLE site_visits_daily (
+  daily_site BIGINT NOT NULL,
+  daily_day_unix BIGINT NOT NULL,
+  daily_visits BIGINT NOT NULL DEFAULT 0,
+  daily_uniques BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_visits_daily PRIMARY KEY (daily_site, daily_day_unix),
+  CONSTRAINT fk_site_visits_daily_site FOREIGN KEY (daily_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+-- Salted visitor hashes, kept only for the current day to deduplicate
+-- site_visits_daily.daily_uniques
+CREATE TABLE site_visitors_daily (
+  visitor_site BIGINT NOT NULL,
+  visitor_day_unix BIGINT NOT NULL,
+  visitor_hash VARCHAR(32) NOT NULL,
+  CONSTRAINT pk_site_visitors_daily PRIMARY KEY (visitor_site, visitor_day_unix, visitor_hash),
+  CONSTRAINT fk_site_visitors_daily_site FOREIGN KEY (visitor_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE TABLE site_referrers_daily (
+  referrer_site BIGINT NOT NULL,
+  referrer_day_unix BIGINT NOT NULL,
+  referrer_host VARCHAR(253) NOT NULL,
+  referrer_utm_source VARCHAR(63) NOT NULL,
+  referrer_utm_medium VARCHAR(63) NOT NULL,
+  referrer_utm_campaign VARCHAR(63) NOT NULL,
+  referrer_visits BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_site_referrers_daily PRIMARY KEY (referrer_site, referrer_day_unix, referrer_host, referrer_utm_source, referrer_utm_medium, referrer_utm_campaign),
+  CONSTRAINT fk_site_referrers_daily_site FOREIGN KEY (referrer_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+-- Full text search documents, one per site, weighted title and tags first,
+-- then description and then the plain text of the published page
+CREATE TABLE site_search (
+  search_site BIGINT PRIMARY KEY,
+  search_title TEXT NOT NULL,
+  search_description TEXT NOT NULL,
+  search_tags TEXT NOT NULL,
+  search_body TEXT NOT NULL,
+  search_es TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', search_title), 'A') ||
+    setweight(to_tsvector('spanish', search_tags), 'A') ||
+    setweight(to_tsvector('spanish', search_description), 'B') ||
+    setweight(to_tsvector('spanish', search_body), 'C')
+  ) STORED,
+  search_en TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', search_title), 'A') ||
+    setweight(to_tsvector('english', search_tags), 'A') ||
+    setweight(to_tsvector('english', search_description), 'B') ||
+    setweight(to_tsvector('english', search_body), 'C')
+  ) STORED,
+  CONSTRAINT fk_site_search_site FOREIGN KEY (search_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+-- Sites queued for review because a field matched a flagged term of the
+-- moderation wordlists, a flag is open until it is resolved
+CREATE TABLE site_flags (
+  flag_id BIGSERIAL PRIMARY KEY,
+  flag_site BIGINT NOT NULL,
+  flag_field VARCHAR(15) NOT NULL,
+  flag_term VARCHAR(63) NOT NULL,
+  flag_lang VARCHAR(7) NOT NULL,
+  flag_created_unix BIGINT NOT NULL,
+  flag_resolved_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_flags_site FOREIGN KEY (flag_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+-- Reports of malicious or infringing sites sent by visitors. The reporter
+-- address is only stored once verified, and ips are stored hashed
+CREATE TABLE site_reports (
+  report_id BIGSERIAL PRIMARY KEY,
+  report_site BIGINT NOT NULL,
+  report_reason VARCHAR(15) NOT NULL,
+  report_details VARCHAR(1023) NOT NULL,
+  report_email VARCHAR(63) NOT NULL DEFAULT '',
+  report_ip_hash VARCHAR(32) NOT NULL,
+  report_created_unix BIGINT NOT NULL,
+  report_resolved_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_reports_site FOREIGN KEY (report_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+-- Sites unpublished by a moderator, the owner cannot publish a site again
+-- while it has a takedown that has not been lifted
+CREATE TABLE site_takedowns (
+  takedown_id BIGSERIAL PRIMARY KEY,
+  takedown_site BIGINT NOT NULL,
+  takedown_moderator BIGINT NOT NULL,
+  takedown_reason VARCHAR(1023) NOT NULL,
+  takedown_created_unix BIGINT NOT NULL,
+  takedown_lifted_unix BIGINT NOT NULL DEFAULT 0,
+  -- Whether the site was published when taken down, lifting the takedown
+  -- restores it
+  takedown_site_published BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_takedowns_site FOREIGN KEY (takedown_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT fk_site_takedowns_moderator FOREIGN KEY (takedown_moderator) REFERENCES users(user_id)
+);
+
+-- Append only log of security relevant actions. Events have no foreign keys
+-- so they outlive the users and sites they refer to, the actor is 0 for
+-- actions of the app itself
+CREATE TABLE audit_events (
+  event_id BIGSERIAL PRIMARY KEY,
+  event_actor BIGINT NOT NULL,
+  event_action VARCHAR(31) NOT NULL,
+  event_target_type VARCHAR(15) NOT NULL,
+  event_target BIGINT NOT NULL,
+  event_ip VARCHAR(63) NOT NULL,
+  event_user_agent VARCHAR(255) NOT NULL,
+  event_payload JSONB NOT NULL DEFAULT '{}',
+  event_created_unix BIGINT NOT NULL
+);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_audit_events_append_only
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
 CREATE TABLE site_objects (
   object_id BIGSERIAL PRIMARY KEY,
   object_site BIGINT NOT NULL DEFAULT 0,
@@ -101,12 +260,79 @@ CREATE TABLE site_banners (
   CONSTRAINT fk_site_banners_object FOREIGN KEY (banner_object) REFERENCES site_objects(object_id)
 );
 
+CREATE TABLE site_revisions (
+  revision_id BIGSERIAL PRIMARY KEY,
+  revision_site BIGINT NOT NULL,
+  revision_title VARCHAR(63) NOT NULL,
+  revision_description VARCHAR(255) NOT NULL,
+  revision_html_gz BYTEA NOT NULL,
+  revision_content_gz BYTEA NOT NULL,
+  revision_created_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_revisions_site FOREIGN KEY (revision_site) REFERENCES sites(site_id) ON DELETE CASCADE
+);
+
+CREATE TABLE site_domains (
+  domain_id BIGSERIAL PRIMARY KEY,
+  domain_site BIGINT NOT NULL,
+  domain_name VARCHAR(253) NOT NULL,
+  domain_token VARCHAR(63) NOT NULL,
+  domain_verified BIGINT NOT NULL DEFAULT 0,
+  domain_created_unix BIGINT NOT NULL,
+  domain_verified_unix BIGINT NOT NULL DEFAULT 0,
+  CONSTRAINT fk_site_domains_site FOREIGN KEY (domain_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT uq_site_domains_site_name UNIQUE (domain_site, domain_name),
+  CONSTRAINT ck_site_domains_verified CHECK (domain_verified IN (0,1))
+);
+
+CREATE TABLE site_pages (
+  page_id BIGSERIAL PRIMARY KEY,
+  page_site BIGINT NOT NULL,
+  page_slug VARCHAR(63) NOT NULL,
+  page_title VARCHAR(63) NOT NULL,
+  page_html_gz BYTEA NOT NULL,
+  page_content_gz BYTEA NOT NULL,
+  page_position BIGINT NOT NULL DEFAULT 0,
+  page_published BIGINT NOT NULL DEFAULT 0,
+  page_created_unix BIGINT NOT NULL,
+  page_modified_unix BIGINT NOT NULL,
+  CONSTRAINT fk_site_pages_site FOREIGN KEY (page_site) REFERENCES sites(site_id) ON DELETE CASCADE,
+  CONSTRAINT uq_site_pages_slug UNIQUE (page_site, page_slug),
+  CONSTRAINT ck_site_pages_published CHECK (page_published IN (0,1))
+);
+
+-- site_tags_json keeps the shape sites used to store their tags in, a JSON
+-- array of {"name", "color"} objects in position order
 CREATE VIEW sites_with_metrics AS
-SELECT s.*, m.*
+SELECT s.*, m.*, COALESCE((
+  SELECT json_agg(
+    json_build_object('name', t.tag_name, 'color', st.site_tag_color)
+    ORDER BY st.site_tag_position
+  )
+  FROM site_tags AS st INNER JOIN tags AS t ON t.tag_id = st.site_tag_tag
+  WHERE st.site_tag_site = s.site_id
+)::text, '[]')::text AS site_tags_json
 FROM sites AS s INNER JOIN site_metrics AS m
 ON s.site_id = m.metric_site;
 
 CREATE INDEX idx_sites_user ON sites(site_user);
 CREATE INDEX idx_sites_published_deleted ON sites(site_published, site_deleted);
+CREATE INDEX idx_sites_publish_at ON sites(site_publish_at_unix) WHERE site_publish_at_unix > 0;
+CREATE INDEX idx_sites_unpublish_at ON sites(site_unpublish_at_unix) WHERE site_unpublish_at_unix > 0;
 CREATE INDEX idx_site_metrics_site ON site_metrics(metric_site);
 CREATE INDEX idx_site_metrics_visits_total ON site_metrics(metric_visits_total);
+CREATE INDEX idx_site_metrics_trending_rank ON site_metrics(metric_trending_rank, metric_site);
+CREATE INDEX idx_site_revisions_site ON site_revisions(revision_site, revision_id);
+CREATE INDEX idx_site_domains_site ON site_domains(domain_site);
+-- Many sites can claim a domain, only the one that verifies it owns it
+CREATE UNIQUE INDEX uq_site_domains_verified_name ON site_domains(domain_name) WHERE domain_verified = 1;
+CREATE INDEX idx_site_domains_pending ON site_domains(domain_created_unix) WHERE domain_verified = 0;
+CREATE INDEX idx_site_pages_site_position ON site_pages(page_site, page_position);
+CREATE INDEX idx_site_visitors_daily_day ON site_visitors_daily(visitor_day_unix);
+CREATE INDEX idx_site_tags_tag ON site_tags(site_tag_tag);
+CREATE INDEX idx_site_search_es ON site_search USING GIN (search_es);
+CREATE INDEX idx_site_search_en ON site_search USING GIN (search_en);
+CREATE UNIQUE INDEX uq_site_flags_open ON site_flags(flag_site, flag_field, flag_term) WHERE flag_resolved_unix = 0;
+CREATE INDEX idx_site_reports_open ON site_reports(report_site) WHERE report_resolved_unix = 0;
+CREATE INDEX idx_site_takedowns_site ON site_takedowns(takedown_site) WHERE takedown_lifted_unix = 0;
+CREATE INDEX idx_audit_events_actor ON audit_events(event_actor, event_id);
+CREATE INDEX idx_audit_events_target ON audit_events(event_target_type, event_target, event_id);
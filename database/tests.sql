@@ -18,31 +18,27 @@ VALUES
 
 INSERT INTO sites (
   site_id, site_user, site_slug, site_title, site_description,
-  site_tags_json, site_html_published, site_html_staging,
+  site_html_published, site_html_staging,
   site_created_unix, site_modified_unix, site_published, site_deleted
 )
 VALUES
 (
   1, 1, 'alice-blog', "Alice's Blog", 'Personal thoughts and design updates.',
-  '[{"name": "design", "color": "orange"}, {"name": "personal", "color": "blue"}]',
   '<h1>Welcome to Alice''s Blog</h1>', '<h1>Draft: Alice''s Blog</h1>',
   1729459200, 1729459200, 1, 0
 ),
 (
   2, 1, 'alice-portfolio', "Alice's Portfolio", 'Showcasing creative work.',
-  '[{"name": "portfolio", "color": "blue"}]',
   '<h1>Alice''s Portfolio</h1>', '<h1>Portfolio Draft</h1>',
   1729459200, 1729459200, 1, 0
 ),
 (
   3, 2, 'bob-coding', "Bob's Coding Corner", 'Programming tutorials and guides.',
-  '[{"name": "coding", "color": "purple"}, {"name": "tutorials", "color": "blue"}]',
   '<h1>Learn to Code with Bob</h1>', '<h1>Draft: Coding Corner</h1>',
   1729459200, 1729459200, 1, 0
 ),
 (
   4, 3, 'carol-photography', "Carol's Photography", 'Travel and lifestyle photography portfolio.',
-  '[{"name": "photography", "color": "cyan"}, {"name": "travel", "color": "green"}]',
   '<h1>Photography by Carol</h1>', '<h1>Draft Photography Page</h1>',
   1729459200, 1729459200, 1, 0
 );
@@ -53,3 +49,23 @@ VALUES
 (2, 2, 980),
 (3, 3, 4120),
 (4, 4, 2210);
+
+INSERT INTO tags (tag_id, tag_name, tag_created_unix)
+VALUES
+(1, 'design', 1729459200),
+(2, 'personal', 1729459200),
+(3, 'portfolio', 1729459200),
+(4, 'coding', 1729459200),
+(5, 'tutorials', 1729459200),
+(6, 'photography', 1729459200),
+(7, 'travel', 1729459200);
+
+INSERT INTO site_tags (site_tag_site, site_tag_tag, site_tag_color, site_tag_position)
+VALUES
+(1, 1, 'orange', 0),
+(1, 2, 'blue', 1),
+(2, 3, 'blue', 0),
+(3, 4, 'purple', 0),
+(3, 5, 'blue', 1),
+(4, 6, 'cyan', 0),
+(4, 7, 'green', 1);
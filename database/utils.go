@@ -5,9 +5,10 @@ package database
 import (
 	"encoding/json"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"html"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/microcosm-cc/bluemonday"
 )
@@ -24,7 +25,8 @@ type Tag struct {
 	Color string `json:"color"`
 }
 
-var tagColors = []string{
+// TagColors is the palette site owners can pick their tag colors from
+var TagColors = []string{
 	"blue",
 	"purple",
 	"cyan",
@@ -34,6 +36,20 @@ var tagColors = []string{
 	"red",
 }
 
+// Roles of users, moderators review reports and take sites down while admins
+// can also manage users, plans and payments from the admin console
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{
+	RoleUser,
+	RoleModerator,
+	RoleAdmin,
+}
+
 func JSONToTags(s string) []Tag {
 	var tags []Tag
 
@@ -50,10 +66,20 @@ func SanitizeHTML(s string) string {
 	return p.Sanitize(s)
 }
 
+// PlainText strips every tag from s, leaving its text content with
+// whitespace collapsed
+func PlainText(s string) string {
+	p := bluemonday.StrictPolicy()
+	p.AddSpaceWhenStrippingTag(true)
+	return strings.Join(strings.Fields(html.UnescapeString(p.Sanitize(s))), " ")
+}
+
+// ParseTags splits a comma or space separated list into lowercase tags
+// without duplicates, colors are left empty for the caller to fill
 func ParseTags(input string) ([]Tag, error) {
 	cleaned := strings.ReplaceAll(input, ",", " ")
 
-	fields := strings.Fields(cleaned)
+	fields := strings.Fields(strings.ToLower(cleaned))
 
 	var tags []Tag
 	for _, f := range fields {
@@ -62,10 +88,10 @@ func ParseTags(input string) ([]Tag, error) {
 				"tags cannot be more than 24 characters long, bad tag: %s", f,
 			)
 		}
-		tags = append(tags, Tag{
-			Name:  f,
-			Color: randomColor(),
-		})
+		if slices.ContainsFunc(tags, func(t Tag) bool { return t.Name == f }) {
+			continue
+		}
+		tags = append(tags, Tag{Name: f})
 	}
 
 	return tags, nil
@@ -93,14 +119,16 @@ func TagsToJSON(tags []Tag) string {
 	return string(b)
 }
 
-func randomColor() string {
-	return tagColors[rand.New(
-		rand.NewSource(
-			time.Now().UnixNano(),
-		),
-	).Intn(
-		len(tagColors),
-	)]
+// DefaultTagColor picks a color from TagColors for a tag, the same name
+// always gets the same color
+func DefaultTagColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return TagColors[h.Sum32()%uint32(len(TagColors))]
+}
+
+func IsTagColor(color string) bool {
+	return slices.Contains(TagColors, color)
 }
 
 func ValidateObjectStrings(bucket, key, mime, md5 string) error {
@@ -0,0 +1,103 @@
+package domains
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	domain  string
+	slug    string
+	expires time.Time
+}
+
+// Cache is a bounded LRU of the sites served under custom domains, an empty
+// slug records a domain that serves no site. Entries expire after ttl. It is
+// safe for concurrent use, a cache with a non positive size stores nothing.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the slug stored for domain, ok is false when there is no entry
+// or it expired.
+func (c *Cache) Get(domain string) (slug string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[domain]
+	if !ok {
+		return "", false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, domain)
+		return "", false
+	}
+
+	c.order.MoveToFront(el)
+
+	return e.slug, true
+}
+
+// Put stores the slug of domain, evicting the least recently used entry when
+// the cache is full.
+func (c *Cache) Put(domain, slug string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+
+	if el, ok := c.items[domain]; ok {
+		e := el.Value.(*cacheEntry)
+		e.slug, e.expires = slug, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[domain] = c.order.PushFront(&cacheEntry{domain: domain, slug: slug, expires: expires})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).domain)
+	}
+}
+
+// Delete removes the entry of domain.
+func (c *Cache) Delete(domain string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[domain]; ok {
+		c.order.Remove(el)
+		delete(c.items, domain)
+	}
+}
+
+// Len returns the number of cached domains, expired ones included.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,93 @@
+// Package domains implements validation and DNS ownership verification of
+// custom domains.
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// RecordPrefix is prepended to a domain to build the name holding its
+// verification TXT record.
+const RecordPrefix = "_conex-verification."
+
+// ValuePrefix is prepended to the verification token in the TXT record value.
+const ValuePrefix = "conex-verification="
+
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// Resolver looks up TXT records, *net.Resolver satisfies it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Normalize lowercases a domain and strips any scheme, path, port and
+// trailing dot, then checks the result is a valid hostname.
+func Normalize(input string) (string, error) {
+	d := strings.ToLower(strings.TrimSpace(input))
+	d = strings.TrimPrefix(d, "https://")
+	d = strings.TrimPrefix(d, "http://")
+
+	if i := strings.IndexAny(d, "/?#"); i >= 0 {
+		d = d[:i]
+	}
+
+	if host, _, err := net.SplitHostPort(d); err == nil {
+		d = host
+	}
+
+	d = strings.TrimSuffix(d, ".")
+
+	if len(d) == 0 || len(d) > 253 || !strings.Contains(d, ".") {
+		return "", ErrInvalidDomain
+	}
+
+	for label := range strings.SplitSeq(d, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return "", ErrInvalidDomain
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "", ErrInvalidDomain
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", ErrInvalidDomain
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// RecordName returns the name where the verification TXT record must be set.
+func RecordName(domain string) string {
+	return RecordPrefix + domain
+}
+
+// RecordValue returns the expected TXT record value for a token.
+func RecordValue(token string) string {
+	return ValuePrefix + token
+}
+
+// Verify reports whether the TXT records of domain contain token.
+func Verify(ctx context.Context, resolver Resolver, domain, token string) (bool, error) {
+	records, err := resolver.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	want := RecordValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
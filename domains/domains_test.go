@@ -0,0 +1,163 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers TXT lookups from a fixed set of records
+type fakeResolver struct {
+	records map[string][]string
+	err     error
+	asked   []string
+}
+
+func (f *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.asked = append(f.asked, name)
+	if f.err != nil {
+		return nil, f.err
+	}
+	records, ok := f.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		records map[string][]string
+		want    bool
+	}{
+		{
+			name:    "matching record",
+			records: map[string][]string{"_conex-verification.example.com": {"conex-verification=token"}},
+			want:    true,
+		},
+		{
+			name:    "matching record among others",
+			records: map[string][]string{"_conex-verification.example.com": {"v=spf1 -all", " conex-verification=token "}},
+			want:    true,
+		},
+		{
+			name:    "other token",
+			records: map[string][]string{"_conex-verification.example.com": {"conex-verification=other"}},
+			want:    false,
+		},
+		{
+			name:    "token without prefix",
+			records: map[string][]string{"_conex-verification.example.com": {"token"}},
+			want:    false,
+		},
+		{
+			name:    "record on the domain itself",
+			records: map[string][]string{"example.com": {"conex-verification=token"}},
+			want:    false,
+		},
+		{
+			name:    "no records",
+			records: nil,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeResolver{records: tt.records}
+
+			got, err := Verify(context.Background(), resolver, "example.com", "token")
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+			if len(resolver.asked) != 1 || resolver.asked[0] != "_conex-verification.example.com" {
+				t.Errorf("Verify() looked up %v", resolver.asked)
+			}
+		})
+	}
+}
+
+func TestVerifyLookupError(t *testing.T) {
+	lookupErr := &net.DNSError{Err: "server misbehaving", Name: "_conex-verification.example.com", IsTemporary: true}
+	resolver := &fakeResolver{err: lookupErr}
+
+	got, err := Verify(context.Background(), resolver, "example.com", "token")
+	if got {
+		t.Error("Verify() = true on lookup error")
+	}
+	if !errors.Is(err, lookupErr) {
+		t.Errorf("Verify() error = %v, want %v", err, lookupErr)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{input: "Example.com", want: "example.com"},
+		{input: " https://www.example.com/path?q=1 ", want: "www.example.com"},
+		{input: "http://example.com:8080", want: "example.com"},
+		{input: "example.com.", want: "example.com"},
+		{input: "localhost", err: ErrInvalidDomain},
+		{input: "-example.com", err: ErrInvalidDomain},
+		{input: "exa_mple.com", err: ErrInvalidDomain},
+		{input: "example..com", err: ErrInvalidDomain},
+		{input: "", err: ErrInvalidDomain},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Normalize(%q) error = %v, want %v", tt.input, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2, time.Minute)
+
+	c.Put("a.com", "a")
+	c.Put("b.com", "")
+	if slug, ok := c.Get("a.com"); !ok || slug != "a" {
+		t.Fatalf("Get(a.com) = %q, %v, want a, true", slug, ok)
+	}
+	if slug, ok := c.Get("b.com"); !ok || slug != "" {
+		t.Fatalf("Get(b.com) = %q, %v, want a cached miss", slug, ok)
+	}
+
+	// a.com is the least recently used and is evicted
+	c.Put("c.com", "c")
+	if _, ok := c.Get("a.com"); ok {
+		t.Error("a.com was not evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	expired := NewCache(2, -time.Second)
+	expired.Put("a.com", "a")
+	if _, ok := expired.Get("a.com"); ok {
+		t.Error("expired entry was returned")
+	}
+	if expired.Len() != 0 {
+		t.Errorf("Len() = %d, want 0 after an expired Get", expired.Len())
+	}
+
+	disabled := NewCache(0, time.Minute)
+	disabled.Put("a.com", "a")
+	if disabled.Len() != 0 {
+		t.Errorf("Len() = %d, want 0 for a disabled cache", disabled.Len())
+	}
+}
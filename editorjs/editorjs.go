@@ -0,0 +1,284 @@
+// Package editorjs implements server-side rendering of Editor.js documents
+// into HTML
+package editorjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+const (
+	BlockHeader    = "header"
+	BlockParagraph = "paragraph"
+	BlockList      = "list"
+	BlockTable     = "table"
+	BlockImage     = "image"
+)
+
+// Document is the output of editor.save() in the browser, it is the same
+// structure stored in database.SiteData.Content
+type Document struct {
+	Time    int64   `json:"time"`
+	Blocks  []Block `json:"blocks"`
+	Version string  `json:"version"`
+}
+
+type Block struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type headerData struct {
+	Text  string `json:"text"`
+	Level int    `json:"level"`
+}
+
+type paragraphData struct {
+	Text string `json:"text"`
+}
+
+type listData struct {
+	Style string          `json:"style"`
+	Items json.RawMessage `json:"items"`
+}
+
+type listItem struct {
+	Content string     `json:"content"`
+	Items   []listItem `json:"items"`
+}
+
+type tableData struct {
+	WithHeadings bool       `json:"withHeadings"`
+	Content      [][]string `json:"content"`
+}
+
+type imageData struct {
+	File struct {
+		URL string `json:"url"`
+	} `json:"file"`
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+}
+
+func Parse(raw []byte) (Document, error) {
+	var doc Document
+
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Document{}, fmt.Errorf("invalid editor.js document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// RenderJSON parses and renders raw Editor.js JSON
+func RenderJSON(raw []byte) (string, error) {
+	doc, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return Render(doc)
+}
+
+// Render walks every block of the document and returns the resulting HTML,
+// one block per line. Inline markup inside texts (bold, italic, links) is kept
+// as produced by Editor.js, callers are expected to sanitize the output.
+func Render(doc Document) (string, error) {
+	var b strings.Builder
+
+	for i, block := range doc.Blocks {
+		out, err := RenderBlock(block)
+		if err != nil {
+			return "", fmt.Errorf("block %d (%s): %w", i, block.Type, err)
+		}
+
+		if out == "" {
+			continue
+		}
+
+		b.WriteString(out)
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// RenderBlock renders a single block, unknown block types render as an empty
+// string
+func RenderBlock(block Block) (string, error) {
+	switch block.Type {
+	case BlockHeader:
+		var d headerData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		return renderHeader(d), nil
+
+	case BlockParagraph:
+		var d paragraphData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(d.Text) == "" {
+			return "", nil
+		}
+		return "<p>" + d.Text + "</p>", nil
+
+	case BlockList:
+		var d listData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		items, err := parseListItems(d.Items)
+		if err != nil {
+			return "", err
+		}
+		return renderList(d.Style, items), nil
+
+	case BlockTable:
+		var d tableData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		return renderTable(d), nil
+
+	case BlockImage:
+		var d imageData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		return renderImage(d), nil
+	}
+
+	return "", nil
+}
+
+func renderHeader(d headerData) string {
+	level := d.Level
+	if level < 1 || level > 6 {
+		level = 2
+	}
+
+	tag := "h" + strconv.Itoa(level)
+
+	return "<" + tag + ">" + d.Text + "</" + tag + ">"
+}
+
+// parseListItems accepts both the nested item objects of @editorjs/list v2 and
+// the plain string items of older versions
+func parseListItems(raw json.RawMessage) ([]listItem, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var items []listItem
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return items, nil
+	}
+
+	var plain []string
+	if err := json.Unmarshal(raw, &plain); err != nil {
+		return nil, err
+	}
+
+	items = make([]listItem, len(plain))
+	for i, p := range plain {
+		items[i] = listItem{Content: p}
+	}
+
+	return items, nil
+}
+
+func renderList(style string, items []listItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	tag := "ul"
+	if style == "ordered" {
+		tag = "ol"
+	}
+
+	var b strings.Builder
+
+	b.WriteString("<" + tag + ">")
+	for _, item := range items {
+		b.WriteString("<li>")
+		b.WriteString(item.Content)
+		b.WriteString(renderList(style, item.Items))
+		b.WriteString("</li>")
+	}
+	b.WriteString("</" + tag + ">")
+
+	return b.String()
+}
+
+func renderTable(d tableData) string {
+	if len(d.Content) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	rows := d.Content
+
+	b.WriteString("<table>")
+	if d.WithHeadings {
+		b.WriteString("<thead><tr>")
+		for _, cell := range rows[0] {
+			b.WriteString("<th>" + cell + "</th>")
+		}
+		b.WriteString("</tr></thead>")
+		rows = rows[1:]
+	}
+
+	if len(rows) > 0 {
+		b.WriteString("<tbody>")
+		for _, row := range rows {
+			b.WriteString("<tr>")
+			for _, cell := range row {
+				b.WriteString("<td>" + cell + "</td>")
+			}
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody>")
+	}
+	b.WriteString("</table>")
+
+	return b.String()
+}
+
+func renderImage(d imageData) string {
+	src := d.File.URL
+	if src == "" {
+		src = d.URL
+	}
+
+	if src == "" {
+		return ""
+	}
+
+	return `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(stripTags(d.Caption)) + `"/>`
+}
+
+func stripTags(s string) string {
+	var b strings.Builder
+
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+
+	return html.UnescapeString(b.String())
+}
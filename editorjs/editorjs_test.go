@@ -0,0 +1,111 @@
+package editorjs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		block Block
+		want  string
+	}{
+		{
+			name:  "header",
+			block: Block{Type: BlockHeader, Data: []byte(`{"text":"Title <i>here</i>","level":3}`)},
+			want:  "<h3>Title <i>here</i></h3>",
+		},
+		{
+			name:  "header with an invalid level",
+			block: Block{Type: BlockHeader, Data: []byte(`{"text":"Title","level":9}`)},
+			want:  "<h2>Title</h2>",
+		},
+		{
+			name:  "paragraph",
+			block: Block{Type: BlockParagraph, Data: []byte(`{"text":"Some <b>bold</b> text"}`)},
+			want:  "<p>Some <b>bold</b> text</p>",
+		},
+		{
+			name:  "empty paragraph",
+			block: Block{Type: BlockParagraph, Data: []byte(`{"text":"  "}`)},
+			want:  "",
+		},
+		{
+			name:  "nested list",
+			block: Block{Type: BlockList, Data: []byte(`{"style":"unordered","items":[{"content":"a","items":[{"content":"b","items":[]}]},{"content":"c","items":[]}]}`)},
+			want:  "<ul><li>a<ul><li>b</li></ul></li><li>c</li></ul>",
+		},
+		{
+			name:  "ordered list of plain items",
+			block: Block{Type: BlockList, Data: []byte(`{"style":"ordered","items":["a","b"]}`)},
+			want:  "<ol><li>a</li><li>b</li></ol>",
+		},
+		{
+			name:  "empty list",
+			block: Block{Type: BlockList, Data: []byte(`{"style":"ordered","items":[]}`)},
+			want:  "",
+		},
+		{
+			name:  "table with headings",
+			block: Block{Type: BlockTable, Data: []byte(`{"withHeadings":true,"content":[["a","b"],["1","2"]]}`)},
+			want:  "<table><thead><tr><th>a</th><th>b</th></tr></thead><tbody><tr><td>1</td><td>2</td></tr></tbody></table>",
+		},
+		{
+			name:  "table without headings",
+			block: Block{Type: BlockTable, Data: []byte(`{"withHeadings":false,"content":[["1","2"]]}`)},
+			want:  "<table><tbody><tr><td>1</td><td>2</td></tr></tbody></table>",
+		},
+		{
+			name:  "image",
+			block: Block{Type: BlockImage, Data: []byte(`{"file":{"url":"https://example.com/a.png?x=1&y=\"2\""},"caption":"A <b>cat</b> &amp; a dog"}`)},
+			want:  `<img src="https://example.com/a.png?x=1&amp;y=&#34;2&#34;" alt="A cat &amp; a dog"/>`,
+		},
+		{
+			name:  "image without a file",
+			block: Block{Type: BlockImage, Data: []byte(`{"caption":"nothing"}`)},
+			want:  "",
+		},
+		{
+			name:  "unknown block",
+			block: Block{Type: "embed", Data: []byte(`{"service":"youtube"}`)},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := RenderBlock(tt.block)
+		if err != nil {
+			t.Errorf("%s: RenderBlock() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: RenderBlock() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	raw := `{"time":1,"version":"2.31.0","blocks":[
+		{"type":"header","data":{"text":"Title","level":1}},
+		{"type":"paragraph","data":{"text":""}},
+		{"type":"paragraph","data":{"text":"Body"}}
+	]}`
+
+	got, err := RenderJSON([]byte(raw))
+	if err != nil {
+		t.Fatalf("RenderJSON() error = %v", err)
+	}
+	if want := "<h1>Title</h1>\n<p>Body</p>\n"; got != want {
+		t.Errorf("RenderJSON() = %q, want %q", got, want)
+	}
+
+	if _, err := RenderJSON([]byte(`{"blocks":`)); err == nil {
+		t.Error("RenderJSON() of invalid JSON did not fail")
+	}
+
+	_, err = RenderJSON([]byte(`{"blocks":[{"type":"header","data":{"text":1}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "block 0 (header)") {
+		t.Errorf("RenderJSON() of an invalid block error = %v, want the block in it", err)
+	}
+}
@@ -0,0 +1,345 @@
+package editorjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdImage     = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)$`)
+	mdListItem  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdTableSep  = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+	mdRule      = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	mdCodeSpan  = regexp.MustCompile("`([^`]+)`")
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold      = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalic    = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	htmlAnchor  = regexp.MustCompile(`(?s)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	htmlLineBrk = regexp.MustCompile(`<br\s*/?>`)
+)
+
+// FromMarkdown converts a Markdown document into Editor.js blocks. Headings,
+// paragraphs, nested lists, tables and standalone images map to their blocks,
+// inline bold, italic and links are kept as the HTML Editor.js produces.
+// Anything else (code fences, quotes) is imported as plain paragraphs.
+func FromMarkdown(src string) Document {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	doc := Document{
+		Time:    time.Now().UnixMilli(),
+		Blocks:  []Block{},
+		Version: "2.31.0",
+	}
+
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			doc.Blocks = append(doc.Blocks, newBlock(BlockParagraph, paragraphData{
+				Text: strings.Join(paragraph, " "),
+			}))
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, html.EscapeString(lines[i]))
+			}
+			if len(code) > 0 {
+				doc.Blocks = append(doc.Blocks, newBlock(BlockParagraph, paragraphData{
+					Text: strings.Join(code, "<br>"),
+				}))
+			}
+
+		case mdHeading.MatchString(trimmed):
+			flush()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			doc.Blocks = append(doc.Blocks, newBlock(BlockHeader, headerData{
+				Text:  inlineMarkdown(m[2]),
+				Level: len(m[1]),
+			}))
+
+		case mdRule.MatchString(trimmed):
+			flush()
+
+		case mdImage.MatchString(trimmed):
+			flush()
+			m := mdImage.FindStringSubmatch(trimmed)
+			var d imageData
+			d.File.URL = m[2]
+			d.Caption = html.EscapeString(m[1])
+			doc.Blocks = append(doc.Blocks, newBlock(BlockImage, d))
+
+		case mdListItem.MatchString(line):
+			flush()
+			start := i
+			for i+1 < len(lines) && mdListItem.MatchString(strings.TrimRight(lines[i+1], " \t")) {
+				i++
+			}
+			doc.Blocks = append(doc.Blocks, parseMarkdownList(lines[start:i+1]))
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && mdTableSep.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			rows := [][]string{splitTableRow(trimmed)}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, splitTableRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			doc.Blocks = append(doc.Blocks, newBlock(BlockTable, tableData{
+				WithHeadings: true,
+				Content:      rows,
+			}))
+
+		case strings.HasPrefix(trimmed, ">"):
+			paragraph = append(paragraph, inlineMarkdown(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))))
+
+		default:
+			paragraph = append(paragraph, inlineMarkdown(trimmed))
+		}
+	}
+
+	flush()
+
+	return doc
+}
+
+func newBlock(kind string, data any) Block {
+	raw, _ := json.Marshal(data)
+	return Block{Type: kind, Data: raw}
+}
+
+// parseMarkdownList builds a list block from consecutive list item lines,
+// deeper indentation nests items under the previous one
+func parseMarkdownList(lines []string) Block {
+	style := "unordered"
+	if m := mdListItem.FindStringSubmatch(lines[0]); m != nil && m[2][0] >= '0' && m[2][0] <= '9' {
+		style = "ordered"
+	}
+
+	type level struct {
+		indent int
+		items  *[]listItem
+	}
+
+	var root []listItem
+	stack := []level{{indent: -1, items: &root}}
+
+	for _, line := range lines {
+		m := mdListItem.FindStringSubmatch(line)
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+
+		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := stack[len(stack)-1].items
+		*parent = append(*parent, listItem{Content: inlineMarkdown(m[3]), Items: []listItem{}})
+
+		last := &(*parent)[len(*parent)-1]
+		stack = append(stack, level{indent: indent, items: &last.Items})
+	}
+
+	items, _ := json.Marshal(root)
+
+	return newBlock(BlockList, listData{
+		Style: style,
+		Items: items,
+	})
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+
+	var cells []string
+	for cell := range strings.SplitSeq(row, "|") {
+		cells = append(cells, inlineMarkdown(strings.TrimSpace(cell)))
+	}
+
+	return cells
+}
+
+// inlineMarkdown escapes text and converts inline Markdown into the markup
+// Editor.js inline tools produce. Code spans are kept verbatim
+func inlineMarkdown(text string) string {
+	var spans []string
+
+	text = mdCodeSpan.ReplaceAllStringFunc(text, func(s string) string {
+		spans = append(spans, html.EscapeString(s[1:len(s)-1]))
+		return "\x00" + strconv.Itoa(len(spans)-1) + "\x00"
+	})
+
+	text = html.EscapeString(text)
+
+	text = mdLink.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = mdBold.ReplaceAllString(text, "<b>$1$2</b>")
+	text = mdItalic.ReplaceAllString(text, "<i>$1$2</i>")
+
+	for i, span := range spans {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", span, 1)
+	}
+
+	return text
+}
+
+// ToMarkdown converts an Editor.js document into Markdown, unknown block types
+// are skipped
+func ToMarkdown(doc Document) (string, error) {
+	var blocks []string
+
+	for i, block := range doc.Blocks {
+		out, err := blockMarkdown(block)
+		if err != nil {
+			return "", fmt.Errorf("block %d (%s): %w", i, block.Type, err)
+		}
+
+		if out == "" {
+			continue
+		}
+
+		blocks = append(blocks, out)
+	}
+
+	if len(blocks) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(blocks, "\n\n") + "\n", nil
+}
+
+func blockMarkdown(block Block) (string, error) {
+	switch block.Type {
+	case BlockHeader:
+		var d headerData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		level := d.Level
+		if level < 1 || level > 6 {
+			level = 2
+		}
+		return strings.Repeat("#", level) + " " + markdownInline(d.Text), nil
+
+	case BlockParagraph:
+		var d paragraphData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(markdownInline(d.Text)), nil
+
+	case BlockList:
+		var d listData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		items, err := parseListItems(d.Items)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		writeMarkdownList(&b, d.Style, items, 0)
+		return strings.TrimRight(b.String(), "\n"), nil
+
+	case BlockTable:
+		var d tableData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		return markdownTable(d), nil
+
+	case BlockImage:
+		var d imageData
+		if err := json.Unmarshal(block.Data, &d); err != nil {
+			return "", err
+		}
+		src := d.File.URL
+		if src == "" {
+			src = d.URL
+		}
+		if src == "" {
+			return "", nil
+		}
+		return "![" + stripTags(d.Caption) + "](" + src + ")", nil
+	}
+
+	return "", nil
+}
+
+func writeMarkdownList(b *strings.Builder, style string, items []listItem, depth int) {
+	for i, item := range items {
+		marker := "- "
+		if style == "ordered" {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+
+		b.WriteString(strings.Repeat("    ", depth))
+		b.WriteString(marker)
+		b.WriteString(markdownInline(item.Content))
+		b.WriteString("\n")
+
+		writeMarkdownList(b, style, item.Items, depth+1)
+	}
+}
+
+func markdownTable(d tableData) string {
+	if len(d.Content) == 0 {
+		return ""
+	}
+
+	row := func(cells []string) string {
+		out := make([]string, len(cells))
+		for i, cell := range cells {
+			out[i] = strings.ReplaceAll(markdownInline(cell), "|", `\|`)
+		}
+		return "| " + strings.Join(out, " | ") + " |"
+	}
+
+	// Markdown tables always have a heading row, a blank one is used when the
+	// table has none
+	rows := d.Content
+	head := make([]string, len(rows[0]))
+	if d.WithHeadings {
+		head = rows[0]
+		rows = rows[1:]
+	}
+
+	lines := []string{row(head), "|" + strings.Repeat(" --- |", len(head))}
+	for _, r := range rows {
+		lines = append(lines, row(r))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownInline converts the inline markup of Editor.js texts back into
+// Markdown, any other tag is dropped
+func markdownInline(text string) string {
+	text = htmlAnchor.ReplaceAllString(text, "[$2]($1)")
+	text = htmlLineBrk.ReplaceAllString(text, "\n")
+
+	text = strings.NewReplacer(
+		"<b>", "**", "</b>", "**",
+		"<strong>", "**", "</strong>", "**",
+		"<i>", "*", "</i>", "*",
+		"<em>", "*", "</em>", "*",
+	).Replace(text)
+
+	return stripTags(text)
+}
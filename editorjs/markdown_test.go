@@ -0,0 +1,133 @@
+package editorjs
+
+import "testing"
+
+// Markdown that converts to blocks and back unchanged
+func TestMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{name: "headers", markdown: "# One\n\n## Two\n\n###### Six"},
+		{name: "paragraphs", markdown: "First paragraph\n\nSecond paragraph"},
+		{name: "inline markup", markdown: "Some **bold**, *italic* and [a link](https://example.com/?a=1&b=2)"},
+		{name: "unordered list", markdown: "- a\n- b\n    - nested\n        - deeper\n- c"},
+		{name: "ordered list", markdown: "1. first\n2. second\n    1. nested"},
+		{name: "table", markdown: "| a | b |\n| --- | --- |\n| 1 | **2** |"},
+		{name: "image", markdown: "![A cat](https://example.com/cat.png)"},
+		{name: "html is text", markdown: "Write <script>alert(1)</script> & <b>tags</b> as text"},
+	}
+
+	for _, tt := range tests {
+		got, err := ToMarkdown(FromMarkdown(tt.markdown))
+		if err != nil {
+			t.Errorf("%s: ToMarkdown() error = %v", tt.name, err)
+			continue
+		}
+		if want := tt.markdown + "\n"; got != want {
+			t.Errorf("%s: round trip = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+// Markdown without a matching block is imported as paragraphs, so what is
+// rendered is checked instead
+func TestFromMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "escapes html",
+			markdown: "<script>alert(1)</script> <b>x</b>",
+			html:     "<p>&lt;script&gt;alert(1)&lt;/script&gt; &lt;b&gt;x&lt;/b&gt;</p>\n",
+		},
+		{
+			name:     "escapes html in headers, lists and tables",
+			markdown: "# <h1>\n\n- <li>\n\n| <td> |\n| --- |\n| & |",
+			html:     "<h1>&lt;h1&gt;</h1>\n<ul><li>&lt;li&gt;</li></ul>\n<table><thead><tr><th>&lt;td&gt;</th></tr></thead><tbody><tr><td>&amp;</td></tr></tbody></table>\n",
+		},
+		{
+			name:     "joins lines of a paragraph",
+			markdown: "one\ntwo\n\nthree",
+			html:     "<p>one two</p>\n<p>three</p>\n",
+		},
+		{
+			name:     "quotes",
+			markdown: "> quoted **text**\n> more",
+			html:     "<p>quoted <b>text</b> more</p>\n",
+		},
+		{
+			name:     "code fence",
+			markdown: "```go\nif a < b {\n\treturn\n}\n```",
+			html:     "<p>if a &lt; b {<br>\treturn<br>}</p>\n",
+		},
+		{
+			name:     "code span",
+			markdown: "run `rm -rf <dir>` **now**",
+			html:     "<p>run rm -rf &lt;dir&gt; <b>now</b></p>\n",
+		},
+		{
+			name:     "link text is escaped",
+			markdown: "[<i>x</i>](https://example.com)",
+			html:     "<p><a href=\"https://example.com\">&lt;i&gt;x&lt;/i&gt;</a></p>\n",
+		},
+		{
+			name:     "underscores",
+			markdown: "__bold__ and _italic_ but snake_case_name",
+			html:     "<p><b>bold</b> and <i>italic</i> but snake_case_name</p>\n",
+		},
+		{
+			name:     "closing hashes and rules",
+			markdown: "## Title ##\n\n---\n\ntext",
+			html:     "<h2>Title</h2>\n<p>text</p>\n",
+		},
+		{
+			name:     "image caption",
+			markdown: "![a <b> & c](https://example.com/a.png \"title\")",
+			html:     "<img src=\"https://example.com/a.png\" alt=\"a &lt;b&gt; &amp; c\"/>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(FromMarkdown(tt.markdown))
+		if err != nil {
+			t.Errorf("%s: Render() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.html {
+			t.Errorf("%s: Render(FromMarkdown()) = %q, want %q", tt.name, got, tt.html)
+		}
+	}
+}
+
+// Editor.js markup that has no Markdown form is dropped on export
+func TestToMarkdown(t *testing.T) {
+	doc := Document{Blocks: []Block{
+		{Type: BlockHeader, Data: []byte(`{"text":"Title <mark>marked</mark>","level":0}`)},
+		{Type: BlockParagraph, Data: []byte(`{"text":"line<br>break &amp; <strong>strong</strong> <em>em</em>"}`)},
+		{Type: BlockList, Data: []byte(`{"style":"unordered","items":["plain","items"]}`)},
+		{Type: BlockTable, Data: []byte(`{"withHeadings":false,"content":[["a|b","c"]]}`)},
+		{Type: BlockImage, Data: []byte(`{"url":"https://example.com/a.png","caption":"<i>cap</i>"}`)},
+		{Type: "embed", Data: []byte(`{}`)},
+	}}
+
+	got, err := ToMarkdown(doc)
+	if err != nil {
+		t.Fatalf("ToMarkdown() error = %v", err)
+	}
+
+	want := "## Title marked\n\n" +
+		"line\nbreak & **strong** *em*\n\n" +
+		"- plain\n- items\n\n" +
+		"|  |  |\n| --- | --- |\n| a\\|b | c |\n\n" +
+		"![cap](https://example.com/a.png)\n"
+	if got != want {
+		t.Errorf("ToMarkdown() = %q, want %q", got, want)
+	}
+
+	if got, err := ToMarkdown(Document{}); err != nil || got != "" {
+		t.Errorf("ToMarkdown() of an empty document = %q, %v, want an empty string", got, err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package feeds writes RSS 2.0 and Atom 1.0 syndication feeds.
+package feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// ContentTypes maps each format to the media type it is served with.
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+}
+
+// Feed is the format agnostic description of a feed. Link is the page the
+// feed describes and Self the URL the feed itself is served at.
+type Feed struct {
+	Title       string
+	Link        string
+	Self        string
+	Description string
+	Language    string
+	Items       []Item
+}
+
+// Item is a single feed entry, Link doubles as its unique id.
+type Item struct {
+	Title   string
+	Link    string
+	Summary string
+	Updated time.Time
+}
+
+// Updated returns the most recent item date, or the zero time for an empty
+// feed.
+func (f Feed) Updated() time.Time {
+	var latest time.Time
+	for _, item := range f.Items {
+		if item.Updated.After(latest) {
+			latest = item.Updated
+		}
+	}
+	return latest
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          rssSelf   `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// WriteRSS writes f as an RSS 2.0 document.
+func WriteRSS(w io.Writer, f Feed) error {
+	doc := rssDoc{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Self:        rssSelf{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+			Description: f.Description,
+			Language:    f.Language,
+		},
+	}
+
+	if updated := f.Updated(); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.Link, IsPermaLink: true},
+			Description: item.Summary,
+			PubDate:     item.Updated.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return write(w, doc)
+}
+
+type atomDoc struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+// WriteAtom writes f as an Atom 1.0 document.
+func WriteAtom(w io.Writer, f Feed) error {
+	updated := f.Updated()
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+
+	doc := atomDoc{
+		Lang:     f.Language,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.Self,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, item := range f.Items {
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:   item.Title,
+			ID:      item.Link,
+			Updated: item.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+			Summary: item.Summary,
+		})
+	}
+
+	return write(w, doc)
+}
+
+// Write writes f in the given format.
+func Write(w io.Writer, format string, f Feed) error {
+	switch format {
+	case FormatRSS:
+		return WriteRSS(w, f)
+	case FormatAtom:
+		return WriteAtom(w, f)
+	}
+	return ErrUnknownFormat
+}
+
+func write(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
@@ -34,8 +34,18 @@ func (h *Handler) Account(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	events, err := h.Queries().GetAuditEventsByUser(ctx, db.GetAuditEventsByUserParams{
+		UserID: session.SessionUser,
+		Lim:    auditEvents,
+	})
+	if err != nil {
+		h.Log().Error("error retrieving audit events", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	header := templates.AccountHeader(tr, user.UserEmail)
-	content := templates.Account(tr, session, user, sessions)
+	content := templates.Account(tr, session, user, sessions, events)
 
 	if err := templates.Base(h.Translator(r), header, content, nil, true).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
@@ -0,0 +1,673 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/database"
+	"app/internal/db"
+	"app/templates"
+)
+
+const (
+	adminSearchLimit   = 50
+	adminPaymentsLimit = 100
+)
+
+// ctxImpersonatorKey holds the session of the admin viewing the app as
+// another user
+const ctxImpersonatorKey ctxKey = "impersonator"
+
+// AdminMiddleware lets only admins through, it must run after the
+// authentication middleware. Anyone else gets a not found so the console is
+// not advertised
+func (h *Handler) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		session, ok := ctx.Value(ctxSessionKey).(db.Session)
+		if !ok {
+			h.Log().Error("error retrieving session from ctx")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		user, err := h.Queries().GetUserByID(ctx, session.SessionUser)
+		if err != nil || user.UserRole != database.RoleAdmin {
+			h.Log().Debug("not an admin", "user", session.SessionUser, "error", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) Admin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	payments, err := h.Queries().GetRecentPayments(ctx, adminPaymentsLimit)
+	if err != nil {
+		h.Log().Error("error loading payments", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	header := templates.AdminHeader(tr)
+	content := templates.AdminConsole(tr, payments)
+
+	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// AdminSearchUsers lists the users whose email contains the query, newest
+// first
+func (h *Handler) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	var payload struct {
+		Query string `json:"admin_query"`
+	}
+
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("datastar")), &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// The query is matched literally, not as a LIKE pattern
+	query := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.TrimSpace(payload.Query))
+
+	users, err := h.Queries().SearchUsers(ctx, db.SearchUsersParams{
+		Query: query,
+		Lim:   adminSearchLimit,
+	})
+	if err != nil {
+		h.Log().Error("error searching users", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.AdminUserResults(tr, users).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// adminUser returns the user in the path of the request
+func (h *Handler) adminUser(r *http.Request) (db.User, int, bool) {
+	userID, err := strconv.ParseInt(r.PathValue("user"), 10, 64)
+	if err != nil {
+		return db.User{}, http.StatusBadRequest, false
+	}
+
+	user, err := h.Queries().GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.Log().Debug("error querying user", "user", userID, "error", err)
+		return db.User{}, http.StatusNotFound, false
+	}
+
+	return user, http.StatusOK, true
+}
+
+func (h *Handler) AdminUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	user, status, ok := h.adminUser(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	plan, err := h.Queries().GetPlan(ctx, user.UserID)
+	if err != nil {
+		h.Log().Error("error loading plan", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.Queries().GetSessionsByUser(ctx, user.UserID)
+	if err != nil {
+		h.Log().Error("error loading sessions", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payments, err := h.Queries().GetPaymentsByUser(ctx, user.UserID)
+	if err != nil {
+		h.Log().Error("error loading payments", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sites, takedowns, err := h.adminSites(ctx, user.UserID)
+	if err != nil {
+		h.Log().Error("error loading sites", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	header := templates.AdminUserHeader(tr, user)
+	content := templates.AdminUser(tr, user, plan, sessions, payments, sites, takedowns)
+
+	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// AdminUpdatePlan sets when the plan of a user is due and whether it is
+// active, as granted by hand instead of through a payment
+func (h *Handler) AdminUpdatePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	session, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Error("error retrieving session from ctx")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user, status, ok := h.adminUser(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		Due    string `json:"plan_due"`
+		Active bool   `json:"plan_active"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid plan request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var due int64
+	if req.Due != "" {
+		loc, _ := time.LoadLocation("America/Costa_Rica")
+		t, err := time.ParseInLocation(time.DateOnly, req.Due, loc)
+		if err != nil {
+			templates.Notice(
+				templates.AdminNoticeID,
+				templates.NoticeWarn,
+				tr("warn"),
+				tr("admin_invalid_date"),
+			).Render(ctx, w)
+			return
+		}
+		// Plans are due at the end of the day
+		due = t.AddDate(0, 0, 1).Unix() - 1
+	}
+
+	plan, err := h.Queries().GetPlan(ctx, user.UserID)
+	if err != nil {
+		h.Log().Error("error loading plan", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	before := plan
+
+	plan.UserPlanModifiedUnix = time.Now().Unix()
+	plan.UserPlanDueUnix = due
+	plan.UserPlanActive = 0
+	if req.Active {
+		plan.UserPlanActive = 1
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdatePlan(ctx, db.UpdatePlanParams{
+		UserPlanModifiedUnix: plan.UserPlanModifiedUnix,
+		UserPlanDueUnix:      plan.UserPlanDueUnix,
+		UserPlanActive:       plan.UserPlanActive,
+		UserPlanID:           plan.UserPlanID,
+	}); err != nil {
+		h.Log().Error("error updating plan", "user", user.UserID, "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditPlanChange,
+		targetType: auditTargetUser,
+		target:     user.UserID,
+		payload: map[string]any{
+			"due":           plan.UserPlanDueUnix,
+			"active":        plan.UserPlanActive,
+			"due_before":    before.UserPlanDueUnix,
+			"active_before": before.UserPlanActive,
+		},
+	}); err != nil {
+		h.Log().Error("error auditing plan change", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.Log().Info("plan updated by admin", "user", user.UserID, "due", plan.UserPlanDueUnix, "active", plan.UserPlanActive)
+
+	templates.AdminPlan(tr, user, plan).Render(ctx, w)
+	templates.Notice(
+		templates.AdminNoticeID,
+		templates.NoticeInfo,
+		tr("success"),
+		tr("admin_plan_updated"),
+	).Render(ctx, w)
+}
+
+// AdminUpdateRole grants a role to a user, admins cannot change their own
+// role so the console always keeps at least one admin
+func (h *Handler) AdminUpdateRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	session, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Error("error retrieving session from ctx")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user, status, ok := h.adminUser(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		Role string `json:"user_role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid role request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !slices.Contains(database.Roles, req.Role) || user.UserID == session.SessionUser || user.UserDeleted == 1 {
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("admin_invalid_role"),
+		).Render(ctx, w)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		UserRole:         req.Role,
+		UserModifiedUnix: time.Now().Unix(),
+		UserID:           user.UserID,
+	}); err != nil {
+		h.Log().Error("error updating role", "user", user.UserID, "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditRoleChange,
+		targetType: auditTargetUser,
+		target:     user.UserID,
+		payload:    map[string]any{"role": req.Role, "role_before": user.UserRole},
+	}); err != nil {
+		h.Log().Error("error auditing role change", "user", user.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.Log().Info("role updated by admin", "user", user.UserID, "role", req.Role, "admin", session.SessionUser)
+
+	templates.Notice(
+		templates.AdminNoticeID,
+		templates.NoticeInfo,
+		tr("success"),
+		tr("admin_role_updated"),
+	).Render(ctx, w)
+}
+
+// AdminImpersonate renders the dashboard as the owner of a session sees it.
+// It is read only since every change still goes through the session of the
+// admin, which does not own the sites shown
+func (h *Handler) AdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionID, err := strconv.ParseInt(r.PathValue("session"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.Queries().GetSession(ctx, sessionID)
+	if err != nil {
+		h.Log().Debug("error querying session", "session", sessionID, "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	admin, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Error("error retrieving session from ctx")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := audit(ctx, h.Queries(), r, auditEvent{
+		actor:      admin.SessionUser,
+		action:     auditSessionImpersonate,
+		targetType: auditTargetSession,
+		target:     target.SessionID,
+		payload:    map[string]any{"user": target.SessionUser, "device": target.SessionDevice},
+	}); err != nil {
+		h.Log().Error("error auditing impersonation", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.Log().Info("admin impersonating session", "admin", admin.SessionUser, "user", target.SessionUser, "session", target.SessionID)
+
+	ctx = context.WithValue(ctx, ctxImpersonatorKey, admin)
+	ctx = context.WithValue(ctx, ctxSessionKey, target)
+
+	h.Dashboard(w, r.WithContext(ctx))
+}
+
+// adminSites returns the sites of a user along with their active takedowns
+func (h *Handler) adminSites(ctx context.Context, user int64) ([]db.SitesWithMetric, []db.GetActiveTakedownsByUserRow, error) {
+	sites, err := h.Queries().GetSitesWithMetricsByUserID(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	takedowns, err := h.Queries().GetActiveTakedownsByUser(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sites, takedowns, nil
+}
+
+func (h *Handler) renderAdminSites(w http.ResponseWriter, r *http.Request, user int64) {
+	ctx := r.Context()
+
+	sites, takedowns, err := h.adminSites(ctx, user)
+	if err != nil {
+		h.Log().Error("error loading sites", "user", user, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.AdminSites(h.Translator(r), sites, takedowns).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// AdminUnpublishSite takes a site down the same way moderators do
+func (h *Handler) AdminUnpublishSite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	session, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Error("error retrieving session from ctx")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"takedown_reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid takedown request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > reportDetailsMax {
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("takedown_reason_required"),
+		).Render(ctx, w)
+		return
+	}
+
+	site, err := h.Queries().GetSiteBySlug(ctx, r.PathValue("site"))
+	if err != nil {
+		h.Log().Debug("error querying site", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := h.takedown(r, site, session.SessionUser, req.Reason); err != nil {
+		h.Log().Error("error taking site down", "site", site.SiteSlug, "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.renderAdminSites(w, r, site.SiteUser)
+}
+
+// AdminRestoreSite lifts the takedowns of a site and publishes it again if
+// it was published when taken down
+func (h *Handler) AdminRestoreSite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	session, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Error("error retrieving session from ctx")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	site, err := h.Queries().GetSiteBySlug(ctx, r.PathValue("site"))
+	if err != nil {
+		h.Log().Debug("error querying site", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	takedowns, err := qtx.LiftTakedowns(ctx, db.LiftTakedownsParams{
+		TakedownLiftedUnix: time.Now().Unix(),
+		TakedownSite:       site.SiteID,
+	})
+	if err != nil {
+		h.Log().Error("error lifting takedowns", "site", site.SiteSlug, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	lifted := len(takedowns)
+
+	// Sites go back to how they were before the first takedown, later ones
+	// find them unpublished already
+	published := slices.Contains(takedowns, 1) && len(site.SiteHtmlGz) > 0
+
+	if published {
+		if err := qtx.PublishSite(ctx, site.SiteID); err != nil {
+			h.Log().Error("error publishing site", "site", site.SiteSlug, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditSiteRestore,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "owner": site.SiteUser, "lifted": lifted, "published": published},
+	}); err != nil {
+		h.Log().Error("error auditing site restore", "site", site.SiteSlug, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		templates.Notice(
+			templates.AdminNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Info("site restored by admin", "site", site.SiteSlug, "lifted", lifted)
+
+	h.renderAdminSites(w, r, site.SiteUser)
+}
+
+// AdminAudit lists every audit event, newest first, optionally only the ones
+// by or on a user and of an action
+func (h *Handler) AdminAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	query := r.URL.Query()
+
+	filter := templates.AuditFilter{
+		Action:  query.Get("action"),
+		Actions: auditActions,
+	}
+	if filter.Action != "" && !slices.Contains(auditActions, filter.Action) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var before int64
+	var err error
+
+	if v := query.Get("user"); v != "" {
+		if filter.User, err = strconv.ParseInt(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if v := query.Get("before"); v != "" {
+		if before, err = strconv.ParseInt(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.Queries().GetAuditEvents(ctx, db.GetAuditEventsParams{
+		UserID: filter.User,
+		Action: filter.Action,
+		Before: before,
+		Lim:    auditEvents,
+	})
+	if err != nil {
+		h.Log().Error("error loading audit events", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// A full page means there may be older events
+	if len(events) == auditEvents {
+		filter.Next = events[len(events)-1].EventID
+	}
+
+	header := templates.AdminHeader(tr)
+	content := templates.AdminAudit(tr, filter, events)
+
+	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
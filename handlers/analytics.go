@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"app/internal/db"
+	"app/templates"
+)
+
+// analyticsRanges are the number of days the visits chart can span, the first
+// one is the default
+var analyticsRanges = []int{30, 90, 365}
+
+// topReferrers is how many referrers each dashboard card lists, over the
+// default range
+const topReferrers = 3
+
+type AnalyticsResponse struct {
+	Site    string         `json:"site"`
+	Days    int            `json:"days"`
+	Total   int64          `json:"total"`
+	Uniques int64          `json:"uniques"`
+	Visits  []AnalyticsDay `json:"visits"`
+}
+
+type AnalyticsDay struct {
+	Date    string `json:"date"`
+	Visits  int64  `json:"visits"`
+	Uniques int64  `json:"uniques"`
+}
+
+// Analytics writes the daily visits of a site as JSON, for the range given in
+// the days query parameter
+func (h *Handler) Analytics(w http.ResponseWriter, r *http.Request) {
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	days := analyticsRange(r)
+
+	series, err := h.dailyVisits(r.Context(), site.SiteUser, site.SiteID, days)
+	if err != nil {
+		h.Log().Error("error querying daily visits", "error", err, "site", site.SiteSlug)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := AnalyticsResponse{
+		Site:   site.SiteSlug,
+		Days:   days,
+		Visits: make([]AnalyticsDay, 0, len(series)),
+	}
+
+	for _, d := range series {
+		resp.Total += d.Visits
+		resp.Uniques += d.Uniques
+		resp.Visits = append(resp.Visits, AnalyticsDay{
+			Date:    d.Day.Format("2006-01-02"),
+			Visits:  d.Visits,
+			Uniques: d.Uniques,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.Log().Error("error encoding analytics", "error", err)
+		return
+	}
+}
+
+// AnalyticsReferrers writes the daily referrers and campaigns of a site as
+// CSV, for the range given in the days query parameter
+func (h *Handler) AnalyticsReferrers(w http.ResponseWriter, r *http.Request) {
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	days := analyticsRange(r)
+
+	rows, err := h.Queries().GetDailyReferrers(r.Context(), db.GetDailyReferrersParams{
+		ReferrerSite: site.SiteID,
+		SinceUnix:    startOfDay(time.Now()).AddDate(0, 0, 1-days).Unix(),
+	})
+	if err != nil {
+		h.Log().Error("error querying daily referrers", "error", err, "site", site.SiteSlug)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+site.SiteSlug+`-referrers.csv"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"date", "referrer", "utm_source", "utm_medium", "utm_campaign", "visits"}); err != nil {
+		h.Log().Error("error writing referrers", "error", err)
+		return
+	}
+
+	for _, row := range rows {
+		if err := cw.Write([]string{
+			time.Unix(row.ReferrerDayUnix, 0).UTC().Format("2006-01-02"),
+			row.ReferrerHost,
+			row.ReferrerUtmSource,
+			row.ReferrerUtmMedium,
+			row.ReferrerUtmCampaign,
+			strconv.FormatInt(row.ReferrerVisits, 10),
+		}); err != nil {
+			h.Log().Error("error writing referrers", "error", err)
+			return
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		h.Log().Error("error writing referrers", "error", err)
+		return
+	}
+}
+
+// analyticsRange returns the days query parameter when it is one of
+// analyticsRanges, or the default range
+func analyticsRange(r *http.Request) int {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || !slices.Contains(analyticsRanges, days) {
+		return analyticsRanges[0]
+	}
+	return days
+}
+
+// dailyVisits returns one entry per day for the last days days, today
+// included, of the given site or of every site of user when site is 0
+func (h *Handler) dailyVisits(ctx context.Context, user, site int64, days int) ([]templates.DailyVisits, error) {
+	since := startOfDay(time.Now()).AddDate(0, 0, 1-days)
+
+	rows, err := h.Queries().GetDailyVisits(ctx, db.GetDailyVisitsParams{
+		SiteUser:  user,
+		SiteID:    site,
+		SinceUnix: since.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[int64]db.GetDailyVisitsRow, len(rows))
+	for _, row := range rows {
+		byDay[row.DailyDayUnix] = row
+	}
+
+	series := make([]templates.DailyVisits, days)
+	for i := range series {
+		day := since.AddDate(0, 0, i)
+		series[i] = templates.DailyVisits{
+			Day:     day,
+			Visits:  byDay[day.Unix()].DailyVisits,
+			Uniques: byDay[day.Unix()].DailyUniques,
+		}
+	}
+
+	return series, nil
+}
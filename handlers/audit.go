@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"app/internal/db"
+	"app/utils"
+)
+
+// Actions recorded in the audit log, each has an audit_<action> translation
+const (
+	auditAccountDelete      = "account_delete"
+	auditEmailChange        = "email_change"
+	auditSessionRevoke      = "session_revoke"
+	auditSessionImpersonate = "session_impersonate"
+	auditSiteDelete         = "site_delete"
+	auditSitePublish        = "site_publish"
+	auditSiteUnpublish      = "site_unpublish"
+	auditPagePublish        = "page_publish"
+	auditSiteTakedown       = "site_takedown"
+	auditSiteRestore        = "site_restore"
+	auditPlanChange         = "plan_change"
+	auditRoleChange         = "role_change"
+)
+
+var auditActions = []string{
+	auditAccountDelete,
+	auditEmailChange,
+	auditSessionRevoke,
+	auditSessionImpersonate,
+	auditSiteDelete,
+	auditSitePublish,
+	auditSiteUnpublish,
+	auditPagePublish,
+	auditSiteTakedown,
+	auditSiteRestore,
+	auditPlanChange,
+	auditRoleChange,
+}
+
+// Kinds of targets of audit events
+const (
+	auditTargetUser    = "user"
+	auditTargetSession = "session"
+	auditTargetSite    = "site"
+)
+
+// auditEvents is how many events are shown in a page of the audit log
+const auditEvents = 50
+
+type auditEvent struct {
+	actor      int64
+	action     string
+	targetType string
+	target     int64
+	payload    map[string]any
+}
+
+// audit records event along with the client of the request, r is nil for
+// actions taken by the app itself, such as scheduled jobs. It is meant to be
+// called with the queries of the transaction of the action, so the event is
+// only kept when the action is
+func audit(ctx context.Context, queries *db.Queries, r *http.Request, event auditEvent) error {
+	payload, err := json.Marshal(event.payload)
+	if err != nil {
+		return err
+	}
+	if event.payload == nil {
+		payload = []byte("{}")
+	}
+
+	var ip, userAgent string
+	if r != nil {
+		ip, userAgent = utils.ClientIP(r), r.UserAgent()
+	}
+
+	return queries.InsertAuditEvent(ctx, db.InsertAuditEventParams{
+		EventActor:       event.actor,
+		EventAction:      event.action,
+		EventTargetType:  event.targetType,
+		EventTarget:      event.target,
+		EventIp:          truncate(ip, 63),
+		EventUserAgent:   truncate(userAgent, 255),
+		EventPayload:     payload,
+		EventCreatedUnix: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/pagecache"
+	"app/utils"
+)
+
+// weakETag hashes every value a response depends on into a weak validator,
+// weak so the gzip and identity encodings share it
+func weakETag(values ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// notModified reports whether the conditional headers of r match the current
+// validators. If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for candidate := range strings.SplitSeq(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of r lists
+// encoding without a zero quality
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for token := range strings.SplitSeq(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(token), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		quality, err := strconv.ParseFloat(q, 64)
+		return err == nil && quality > 0
+	}
+	return false
+}
+
+// writePage answers with a rendered site page, or a 304 when the client copy
+// is still valid. Brotli is preferred over gzip when both are accepted
+func (h *Handler) writePage(w http.ResponseWriter, r *http.Request, page pagecache.Page) {
+	w.Header().Set("ETag", page.ETag)
+	w.Header().Set("Last-Modified", page.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Vary", "Accept-Encoding, Accept-Language")
+	if config.SiteCacheControl != "" {
+		w.Header().Set("Cache-Control", config.SiteCacheControl)
+	}
+
+	if notModified(r, page.ETag, page.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := page.Gzip
+
+	switch {
+	case acceptsEncoding(r, "br"):
+		w.Header().Set("Content-Encoding", "br")
+		body = page.Brotli
+	case acceptsEncoding(r, "gzip"):
+		w.Header().Set("Content-Encoding", "gzip")
+	default:
+		html, err := utils.Gunzip(page.Gzip)
+		if err != nil {
+			h.Log().Error("error gunzip cached page", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body = html
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+	if _, err := w.Write(body); err != nil {
+		h.Log().Error("error writing page", "error", err)
+		return
+	}
+}
@@ -34,8 +34,69 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	analytics := templates.Analytics{
+		Days:   analyticsRange(r),
+		Ranges: analyticsRanges,
+	}
+
+	var siteID int64
+	if slug := r.URL.Query().Get("site"); slug != "" {
+		for _, s := range sites {
+			if s.SiteSlug == slug {
+				analytics.Site = s.SiteSlug
+				siteID = s.SiteID
+			}
+		}
+	}
+
+	analytics.Series, err = h.dailyVisits(ctx, session.SessionUser, siteID, analytics.Days)
+	if err != nil {
+		h.Log().Error("error loading daily visits", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	top, err := h.Queries().GetTopReferrersByUser(ctx, db.GetTopReferrersByUserParams{
+		SiteUser:  session.SessionUser,
+		SinceUnix: startOfDay(time.Now()).AddDate(0, 0, 1-analyticsRanges[0]).Unix(),
+		PerSite:   topReferrers,
+	})
+	if err != nil {
+		h.Log().Error("error loading top referrers", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	referrers := make(map[int64][]templates.Referrer)
+	for _, ref := range top {
+		referrers[ref.ReferrerSite] = append(referrers[ref.ReferrerSite], templates.Referrer{
+			Name:   ref.ReferrerName,
+			Visits: ref.ReferrerVisits,
+		})
+	}
+
+	takedowns, err := h.Queries().GetActiveTakedownsByUser(ctx, session.SessionUser)
+	if err != nil {
+		h.Log().Error("error loading takedowns", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	header := templates.DashboardHeader(h.Translator(r))
-	content := templates.Dashboard(h.Translator(r), sites)
+
+	// Admins viewing the dashboard of someone else get a header saying so
+	// instead of the links to their own account
+	if _, ok := ctx.Value(ctxImpersonatorKey).(db.Session); ok {
+		user, err := h.Queries().GetUserByID(ctx, session.SessionUser)
+		if err != nil {
+			h.Log().Error("error loading impersonated user", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		header = templates.ImpersonationHeader(h.Translator(r), user)
+	}
+
+	content := templates.Dashboard(h.Translator(r), sites, analytics, referrers, takedowns)
 
 	if err := templates.Base(h.Translator(r), header, content, nil, true).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
@@ -80,6 +141,21 @@ func (h *Handler) NewSite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	matches := h.moderate(map[string]string{
+		"slug":  endpoint,
+		"title": name,
+	})
+	if rejected(matches) {
+		h.Log().Debug("rejected new site", "field", matches[0].field, "term", matches[0].Term)
+		templates.Notice(
+			templates.NewSiteNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("moderation_rejected"),
+		).Render(ctx, w)
+		return
+	}
+
 	for _, e := range config.Endpoints {
 		used := strings.ReplaceAll(e, config.Endpoints[config.RootPath], "")
 		used = strings.ReplaceAll(used, "/", "")
@@ -178,7 +254,6 @@ func (h *Handler) NewSite(w http.ResponseWriter, r *http.Request) {
 		SiteUser:         session.SessionUser,
 		SiteSlug:         endpoint,
 		SiteTitle:        name,
-		SiteTagsJson:     "",
 		SiteDescription:  "",
 		SiteHtmlGz:       []byte{},
 		SiteCreatedUnix:  now,
@@ -202,6 +277,12 @@ func (h *Handler) NewSite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := flagSite(ctx, qtx, siteID, matches); err != nil {
+		h.Log().Error("error flagging site", "site", endpoint, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		h.Log().Error("error tx commit", "site", endpoint, "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/domains"
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+)
+
+const (
+	maxDomainsPerSite   = 2
+	domainCacheTTL      = time.Minute
+	domainCacheSize     = 4096
+	domainVerifyTimeout = 5 * time.Second
+	// domainClaimTTL is how long a site can hold an unverified domain
+	domainClaimTTL = 72 * time.Hour
+)
+
+// domainSlug returns the slug of the site served under a verified custom
+// domain. Lookups, including misses, are cached for domainCacheTTL, the hosts
+// of the app and names that cannot be domains are never looked up
+func (h *Handler) domainSlug(ctx context.Context, host string) (string, bool) {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+
+	if slices.Contains(config.Hosts, host) {
+		return "", false
+	}
+	if _, err := domains.Normalize(host); err != nil {
+		return "", false
+	}
+
+	if slug, ok := h.domains.Get(host); ok {
+		return slug, slug != ""
+	}
+
+	slug, err := h.Queries().GetSlugByVerifiedDomain(ctx, db.GetSlugByVerifiedDomainParams{
+		DomainName:      host,
+		UserPlanDueUnix: time.Now().Unix(),
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.Log().Error("error querying custom domain", "error", err)
+			return "", false
+		}
+		slug = ""
+	}
+
+	h.domains.Put(host, slug)
+
+	return slug, slug != ""
+}
+
+// PruneDomains deletes the domain claims that were not verified in time. It is
+// meant to be run periodically by the scheduler
+func (h *Handler) PruneDomains(ctx context.Context) error {
+	return h.Queries().DeletePendingDomainsBefore(ctx, time.Now().Add(-domainClaimTTL).Unix())
+}
+
+// domainPassthrough are the endpoints sites link to, they are served by the
+// app under custom domains too. Pages cannot use their names as slugs
+var domainPassthrough = []config.Endpoint{
+	config.AssetsPath,
+	config.FeedsPath,
+	config.ReportPath,
+}
+
+// CustomDomainMiddleware serves the matching site at the root path, and its
+// pages below it, when the request host is a verified custom domain. It wraps
+// the whole router so app routes never shadow the pages of a site, only the
+// endpoints in domainPassthrough reach the router
+func (h *Handler) CustomDomainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slug, ok := h.domainSlug(r.Context(), utils.Host(r))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, endpoint := range domainPassthrough {
+			if strings.HasPrefix(r.URL.Path, config.Endpoints[endpoint]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.renderSite(w, r, slug, strings.Trim(r.URL.Path, "/"), "")
+	})
+}
+
+// renderDomains renders the custom domains section of a site settings
+func (h *Handler) renderDomains(w http.ResponseWriter, r *http.Request, site db.Site) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	list, err := h.Queries().GetDomainsBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying domains", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Domains(tr, site.SiteSlug, list).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	plan, err := h.Queries().GetPlan(ctx, site.SiteUser)
+	if err != nil {
+		h.Log().Error("error querying plan", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if plan.UserPlanActive != 1 || time.Now().Unix() > plan.UserPlanDueUnix {
+		h.Log().Debug("tried to add a domain without required plan", "user", site.SiteUser)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeInfo,
+			tr("info"),
+			tr("domains_upgrade"),
+		).Render(ctx, w)
+		return
+	}
+
+	var req struct {
+		Domain string `json:"domain"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid domain request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	name, err := domains.Normalize(req.Domain)
+	if err != nil {
+		h.Log().Debug("invalid domain", "domain", req.Domain)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_invalid"),
+		).Render(ctx, w)
+		return
+	}
+
+	existing, err := h.Queries().GetDomainsBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying domains", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(existing) >= maxDomainsPerSite {
+		h.Log().Debug("site reached max domains", "site_id", site.SiteID)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeInfo,
+			tr("info"),
+			tr("domains_limit"),
+		).Render(ctx, w)
+		return
+	}
+
+	if _, err := h.Queries().GetVerifiedDomainSite(ctx, name); err == nil {
+		h.Log().Debug("domain already verified by a site", "domain", name)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_unavailable"),
+		).Render(ctx, w)
+		return
+	}
+
+	token, err := utils.RandomString()
+	if err != nil {
+		h.Log().Error("error generating domain token", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.Queries().InsertDomain(ctx, db.InsertDomainParams{
+		DomainSite:        site.SiteID,
+		DomainName:        name,
+		DomainToken:       token,
+		DomainCreatedUnix: time.Now().Unix(),
+	}); err != nil {
+		h.Log().Debug("error inserting domain", "error", err, "domain", name)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_unavailable"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.Log().Debug("added domain", "site_id", site.SiteID, "domain", name)
+
+	templates.NoticeEmpty(templates.DomainsNoticeID).Render(ctx, w)
+	h.renderDomains(w, r, site)
+}
+
+func (h *Handler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	domainID, err := strconv.ParseInt(r.PathValue("domain"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.Queries().GetDomain(ctx, db.GetDomainParams{
+		DomainID:   domainID,
+		DomainSite: site.SiteID,
+	})
+	if err != nil {
+		h.Log().Debug("error querying domain", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, domainVerifyTimeout)
+	defer cancel()
+
+	verified, err := domains.Verify(lookupCtx, h.Resolver(), domain.DomainName, domain.DomainToken)
+	if err != nil {
+		h.Log().Debug("error looking up domain", "error", err, "domain", domain.DomainName)
+	}
+
+	if !verified {
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_not_verified"),
+		).Render(ctx, w)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	// Other sites may hold a claim on the same domain, the first to verify
+	// owns it. The unique index on verified names settles concurrent ones
+	owner, err := qtx.GetVerifiedDomainSite(ctx, domain.DomainName)
+	if err == nil && owner != site.SiteID {
+		h.Log().Debug("domain already verified by a site", "domain", domain.DomainName, "owner", owner)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_unavailable"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := qtx.VerifyDomain(ctx, db.VerifyDomainParams{
+		DomainVerifiedUnix: time.Now().Unix(),
+		DomainID:           domain.DomainID,
+	}); err != nil {
+		h.Log().Debug("error verifying domain", "error", err, "domain", domain.DomainName)
+		templates.Notice(
+			templates.DomainsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("domains_unavailable"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := qtx.DeletePendingDomainsByName(ctx, domain.DomainName); err != nil {
+		h.Log().Error("error deleting domain claims", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.domains.Delete(domain.DomainName)
+
+	h.Log().Debug("verified domain", "site_id", site.SiteID, "domain", domain.DomainName)
+
+	templates.NoticeEmpty(templates.DomainsNoticeID).Render(ctx, w)
+	h.renderDomains(w, r, site)
+}
+
+func (h *Handler) DeleteDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	domainID, err := strconv.ParseInt(r.PathValue("domain"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.Queries().GetDomain(ctx, db.GetDomainParams{
+		DomainID:   domainID,
+		DomainSite: site.SiteID,
+	})
+	if err != nil {
+		h.Log().Debug("error querying domain", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := h.Queries().DeleteDomain(ctx, db.DeleteDomainParams{
+		DomainID:   domain.DomainID,
+		DomainSite: site.SiteID,
+	}); err != nil {
+		h.Log().Error("error deleting domain", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.domains.Delete(domain.DomainName)
+
+	h.Log().Debug("deleted domain", "site_id", site.SiteID, "domain", domain.DomainName)
+
+	h.renderDomains(w, r, site)
+}
@@ -10,15 +10,14 @@ import (
 
 	"app/config"
 	"app/database"
+	"app/editorjs"
 	"app/internal/db"
 	"app/templates"
 	"app/utils"
 )
 
 const (
-	MaxHTMLSize             = 10 * 1024000 // 10MB
-	forwardedProtoHeaderKey = "X-Forwarded-Proto"
-	forwardedHostHeaderKey  = "X-Forwarded-Host"
+	MaxHTMLSize = 10 * 1024000 // 10MB
 )
 
 type SyncResponse struct {
@@ -30,6 +29,13 @@ type SyncRequest struct {
 	LocalData database.SiteData `json:"localData"`
 }
 
+type PublishData struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Slug        string `json:"slug"`
+	Content     string `json:"content"` // Editor.js JSON document
+}
+
 func (h *Handler) Editor(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -82,20 +88,65 @@ func (h *Handler) Editor(w http.ResponseWriter, r *http.Request) {
 		bannerURL = config.S3PublicURL + "/" + object.ObjectKey
 	}
 
-	tr := h.Translator(r)
+	// Seed the editor with the pending draft, or the published content when
+	// there are no unpublished changes
+	hasDraft := false
+	initial := database.SiteData{
+		Title:       site.SiteTitle,
+		Description: site.SiteDescription,
+	}
+	contentGz := site.SiteContentGz
 
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
+	draft, err := h.Queries().GetDraft(ctx, site.SiteID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.Log().Error("error loading draft", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	} else {
+		hasDraft = true
+		initial.Title = draft.DraftTitle
+		initial.Description = draft.DraftDescription
+		contentGz = draft.DraftContentGz
 	}
-	hostURL := scheme + "://" + r.Host
-	siteURL := hostURL + config.Endpoints[config.RootPath] + site.SiteSlug
 
-	header := templates.EditorHeader(tr, site, bannerURL)
+	if len(contentGz) > 0 {
+		content, err := utils.Gunzip(contentGz)
+		if err != nil {
+			h.Log().Error("error gunzip editor content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		initial.Content = json.RawMessage(content)
+	}
+
+	siteDomains, err := h.Queries().GetDomainsBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error loading domains", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pages, err := h.Queries().GetPagesBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error loading pages", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tr := h.Translator(r)
+
+	siteURL := utils.HostURL(r) + config.Endpoints[config.RootPath] + site.SiteSlug
+
+	header := templates.EditorHeader(tr, site, bannerURL, hasDraft)
 	content := templates.Editor(
 		tr,
 		site,
 		siteURL,
+		initial,
+		siteDomains,
+		pages,
 	)
 
 	templates.Base(tr, header, content, nil, true).Render(ctx, w)
@@ -117,13 +168,6 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type PublishData struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Slug        string `json:"slug"`
-		Content     string `json:"content"`
-	}
-
 	var data PublishData
 
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -167,7 +211,105 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sanitized := database.SanitizeHTML(data.Content)
+	if down, err := h.takenDown(ctx, site.SiteID); err != nil || down {
+		h.Log().Debug("cannot publish site taken down", "site", site.SiteSlug, "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("takedown_cannot_publish"),
+		).Render(ctx, w)
+		return
+	}
+
+	contentGz, err := utils.Gzip([]byte(data.Content))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.Log().Debug("failed to gzip content", "error", err)
+		return
+	}
+
+	if len(data.Title) > 63 || len(data.Description) > 255 || len(contentGz) > MaxHTMLSize {
+		h.Log().Debug("exceeds capacity")
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("publish_too_large"),
+		).Render(ctx, w)
+		return
+	}
+
+	keep, err := h.revisionsLimit(ctx, session.SessionUser)
+	if err != nil {
+		h.Log().Error("error querying plan", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	now := time.Now().Unix()
+
+	// The editor state is saved as a draft first, and that draft is what gets
+	// promoted, so the published site never differs from the stored draft
+	if err := qtx.UpsertDraft(ctx, db.UpsertDraftParams{
+		DraftSite:         site.SiteID,
+		DraftTitle:        data.Title,
+		DraftDescription:  data.Description,
+		DraftContentGz:    contentGz,
+		DraftModifiedUnix: now,
+	}); err != nil {
+		h.Log().Error("error saving draft", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	draft, err := qtx.GetDraft(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying draft", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	content, err := utils.Gunzip(draft.DraftContentGz)
+	if err != nil {
+		h.Log().Error("error gunzip draft", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rendered, err := editorjs.RenderJSON(content)
+	if err != nil {
+		h.Log().Debug("invalid editor content", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	sanitized := database.SanitizeHTML(rendered)
 
 	sanitizedGz, err := utils.Gzip([]byte((sanitized)))
 	if err != nil {
@@ -176,7 +318,7 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(data.Title) > 63 || len(data.Description) > 255 || len(sanitizedGz) > MaxHTMLSize {
+	if len(sanitizedGz) > MaxHTMLSize {
 		h.Log().Debug("exceeds capacity")
 		templates.Notice(
 			templates.PublishNoticeID,
@@ -187,14 +329,37 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Queries().UpdateSite(ctx, db.UpdateSiteParams{
+	matches := h.moderate(map[string]string{
+		"title":       draft.DraftTitle,
+		"description": draft.DraftDescription,
+		"content":     database.PlainText(sanitized),
+	})
+	if rejected(matches) {
+		h.Log().Debug("rejected site content", "site", site.SiteSlug, "field", matches[0].field, "term", matches[0].Term)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("moderation_rejected"),
+		).Render(ctx, w)
+		return
+	}
+
+	// Sites scheduled for a later date keep their content hidden until the
+	// scheduler publishes them
+	var published int64 = 1
+	if site.SitePublishAtUnix > now {
+		published = 0
+	}
+
+	if err := qtx.UpdateSite(ctx, db.UpdateSiteParams{
 		SiteID:           site.SiteID,
-		SiteTitle:        data.Title,
-		SiteDescription:  data.Description,
-		SiteTagsJson:     site.SiteTagsJson,
+		SiteTitle:        draft.DraftTitle,
+		SiteDescription:  draft.DraftDescription,
 		SiteHtmlGz:       sanitizedGz,
-		SiteModifiedUnix: time.Now().Unix(),
-		SitePublished:    1,
+		SiteContentGz:    draft.DraftContentGz,
+		SiteModifiedUnix: now,
+		SitePublished:    published,
 		SiteDeleted:      0,
 	}); err != nil {
 		h.Log().Debug("error updating site", "error", err)
@@ -207,20 +372,81 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.Log().Debug("updated site", "site_id", site.SiteID, "site_html_published", data.Content)
+	if err := indexSite(ctx, qtx, site.SiteID, draft.DraftTitle, draft.DraftDescription, sanitizedGz); err != nil {
+		h.Log().Error("error indexing site", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := flagSite(ctx, qtx, site.SiteID, matches); err != nil {
+		h.Log().Error("error flagging site", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := saveRevision(ctx, qtx, db.InsertRevisionParams{
+		RevisionSite:        site.SiteID,
+		RevisionTitle:       draft.DraftTitle,
+		RevisionDescription: draft.DraftDescription,
+		RevisionHtmlGz:      sanitizedGz,
+		RevisionContentGz:   draft.DraftContentGz,
+		RevisionCreatedUnix: now,
+	}, keep); err != nil {
+		h.Log().Error("error saving revision", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := qtx.DeleteDraft(ctx, site.SiteID); err != nil {
+		h.Log().Error("error deleting promoted draft", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditSitePublish,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "title": draft.DraftTitle, "scheduled": published == 0},
+	}); err != nil {
+		h.Log().Error("error auditing site publishing", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	hostURL := scheme + "://" + r.Host
-	siteURL := hostURL + config.Endpoints[config.RootPath] + site.SiteSlug
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Debug("updated site", "site_id", site.SiteID, "site_html_published", sanitized)
+
+	siteURL := utils.HostURL(r) + config.Endpoints[config.RootPath] + site.SiteSlug
 
 	if err := templates.UnpublishSite(
 		tr,
 		data.Slug,
 		siteURL,
-		true,
+		published == 1,
 	).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -230,11 +456,15 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 	if err := templates.ShowInHomeButton(
 		tr,
 		site.SiteHomePage == 1,
-		true,
+		published == 1,
 	).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering new shown status")
 	}
 
+	if err := templates.DraftStatus(tr, false).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering draft status", "error", err)
+	}
+
 	if err := templates.NoticeEmpty(templates.PublishNoticeID).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -242,6 +472,59 @@ func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var data PublishData
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.Log().Debug("invalid draft request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	contentGz, err := utils.Gzip([]byte(data.Content))
+	if err != nil {
+		h.Log().Debug("failed to gzip content", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(data.Title) > 63 || len(data.Description) > 255 || len(contentGz) > MaxHTMLSize {
+		h.Log().Debug("draft exceeds capacity")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := h.Queries().UpsertDraft(ctx, db.UpsertDraftParams{
+		DraftSite:         site.SiteID,
+		DraftTitle:        data.Title,
+		DraftDescription:  data.Description,
+		DraftContentGz:    contentGz,
+		DraftModifiedUnix: time.Now().Unix(),
+	}); err != nil {
+		h.Log().Error("error saving draft", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.Log().Debug("saved draft", "site_id", site.SiteID)
+
+	if err := templates.DraftStatus(tr, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering draft status", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *Handler) EditorUnpublish(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -277,24 +560,43 @@ func (h *Handler) EditorUnpublish(w http.ResponseWriter, r *http.Request) {
 	}
 	h.Log().Debug("session user is site owner")
 
-	if err := h.Queries().UnpublishSite(ctx, site.SiteID); err != nil {
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UnpublishSite(ctx, site.SiteID); err != nil {
 		h.Log().Error("error unpublishing site")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	scheme := r.Header.Get(forwardedProtoHeaderKey)
-	if scheme == "" {
-		scheme = "http"
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditSiteUnpublish,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug},
+	}); err != nil {
+		h.Log().Error("error auditing site unpublishing", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
-	host := r.Header.Get(forwardedHostHeaderKey)
-	if host == "" {
-		host = r.Host
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
-	hostURL := scheme + "://" + host
-	siteURL := hostURL + config.Endpoints[config.RootPath] + site.SiteSlug
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	siteURL := utils.HostURL(r) + config.Endpoints[config.RootPath] + site.SiteSlug
 
 	tr := h.Translator(r)
 
@@ -686,6 +988,8 @@ func (h *Handler) UploadBanner(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.PageCache().Invalidate(site.SiteSlug)
+
 	imageComp := templates.Image(config.S3PublicURL + "/" + obj.ObjectKey)
 
 	if err := templates.Div(
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/templates"
+	"app/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// exportAssetsDir is the directory inside the archive holding site objects
+const exportAssetsDir = "assets/"
+
+// ExportSite streams a ZIP archive with the rendered site and every object it
+// owns. Links to storage are rewritten so the archive works offline, the
+// stylesheet keeps pointing to this server
+func (h *Handler) ExportSite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	owned, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	site, err := h.Queries().GetSiteWithMetrics(ctx, owned.SiteSlug)
+	if err != nil {
+		h.Log().Error("error querying site with metrics", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	objects, err := h.Queries().GetObjectsBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying objects", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	bannerURL := ""
+
+	banner, err := h.Queries().GetBanner(ctx, site.SiteID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.Log().Error("error loading banner", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	} else {
+		object, err := h.Queries().GetObjectByID(ctx, banner.BannerObject)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				h.Log().Error("error loading banner", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		bannerURL = config.S3PublicURL + "/" + object.ObjectKey
+	}
+
+	head := templates.SiteHead{
+		Title:       site.SiteTitle,
+		Description: site.SiteDescription,
+	}
+
+	header := templates.SiteHeader(tr, site, bannerURL, false, nil)
+
+	var page bytes.Buffer
+	if err := templates.Base(tr, header, templates.Site(site), &head, false).Render(ctx, &page); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	links := strings.NewReplacer(
+		config.S3PublicURL+"/"+site.SiteSlug+"/", exportAssetsDir,
+		config.Endpoints[config.AssetsPath], utils.HostURL(r)+config.Endpoints[config.AssetsPath],
+	)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+site.SiteSlug+`.zip"`)
+
+	// The archive is only closed once every entry is written. Closing it
+	// writes the central directory, which would make a partial download look
+	// like a complete archive
+	archive := zip.NewWriter(w)
+
+	index, err := archive.Create("index.html")
+	if err != nil {
+		h.Log().Error("error creating zip entry", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if _, err := links.WriteString(index, page.String()); err != nil {
+		h.Log().Error("error writing zip entry", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	// Headers are already sent, a failing object aborts the response so the
+	// client sees a failed download instead of a silently incomplete one
+	for _, obj := range objects {
+		name := exportAssetsDir + strings.TrimPrefix(obj.ObjectKey, site.SiteSlug+"/")
+
+		out, err := h.S3().GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(obj.ObjectBucket),
+			Key:    aws.String(obj.ObjectKey),
+		})
+		if err != nil {
+			h.Log().Error("error downloading object", "error", err, "key", obj.ObjectKey)
+			panic(http.ErrAbortHandler)
+		}
+
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     path.Clean(name),
+			Method:   zip.Deflate,
+			Modified: time.Unix(obj.ObjectModifiedUnix, 0),
+		})
+		if err != nil {
+			out.Body.Close()
+			h.Log().Error("error creating zip entry", "error", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		_, err = io.Copy(entry, out.Body)
+		out.Body.Close()
+		if err != nil {
+			h.Log().Error("error writing zip entry", "error", err, "key", obj.ObjectKey)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		h.Log().Error("error closing zip", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	h.Log().Debug("exported site", "site_id", site.SiteID, "objects", len(objects))
+}
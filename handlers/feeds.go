@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"app/config"
+	"app/feeds"
+	"app/internal/db"
+	"app/utils"
+)
+
+const feedItems int32 = 50
+
+func (h *Handler) Feed(w http.ResponseWriter, r *http.Request) {
+	sites, err := h.Queries().GetFeedSites(r.Context(), feedItems)
+	if err != nil {
+		h.Log().Error("error querying feed sites", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tr := h.Translator(r)
+
+	h.writeFeed(w, r, config.AppTitle, tr("home_description"), sites)
+}
+
+func (h *Handler) TagFeed(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	if tag == "" || len(tag) > 24 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	sites, err := h.Queries().GetFeedSitesByTag(r.Context(), db.GetFeedSitesByTagParams{
+		Tag:      tag,
+		MaxItems: feedItems,
+	})
+	if err != nil {
+		h.Log().Error("error querying feed sites by tag", "error", err, "tag", tag)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tr := h.Translator(r)
+
+	h.writeFeed(w, r, config.AppTitle+" | #"+tag, tr("feeds_tag_description")+" #"+tag, sites)
+}
+
+// UserFeed lists the sites by the author of the site in the {site} path
+// value. Feeds are keyed by a public site rather than the user id, so they
+// cannot be used to enumerate users
+func (h *Handler) UserFeed(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("site")
+	if slug == "" || len(slug) > 63 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	sites, err := h.Queries().GetFeedSitesByAuthorOf(r.Context(), db.GetFeedSitesByAuthorOfParams{
+		Slug:     slug,
+		MaxItems: feedItems,
+	})
+	if err != nil {
+		h.Log().Error("error querying feed sites by author", "error", err, "site", slug)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tr := h.Translator(r)
+
+	h.writeFeed(w, r, config.AppTitle+" | "+tr("feeds_user_title"), tr("feeds_user_description"), sites)
+}
+
+// writeFeed renders sites as the feed format requested in the {format} path
+// value, the feed self link is the request URL
+func (h *Handler) writeFeed(w http.ResponseWriter, r *http.Request, title, description string, sites []db.SitesWithMetric) {
+	format := r.PathValue("format")
+
+	contentType, ok := feeds.ContentTypes[format]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	host := utils.HostURL(r)
+
+	feed := feeds.Feed{
+		Title:       title,
+		Link:        host + config.Endpoints[config.RootPath],
+		Self:        host + r.URL.Path,
+		Description: description,
+		Language:    h.Translator(r)("lang"),
+	}
+
+	for _, s := range sites {
+		feed.Items = append(feed.Items, feeds.Item{
+			Title:   s.SiteTitle,
+			Link:    host + config.Endpoints[config.RootPath] + s.SiteSlug,
+			Summary: s.SiteDescription,
+			Updated: time.Unix(s.SiteModifiedUnix, 0),
+		})
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	if err := feeds.Write(w, format, feed); err != nil {
+		h.Log().Error("error writing feed", "error", err, "format", format)
+		return
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -12,16 +13,22 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"app/domains"
 	"app/i18n"
 	"app/internal/db"
+	"app/moderation"
+	"app/pagecache"
 	"app/sessions"
 	"app/utils/smtp"
+	"app/visits"
 )
 
 type Handler struct {
 	params     HandlerParams
 	Translator func(*http.Request) func(string) string
 	Sessions   *sessions.Store[db.Session]
+	visits     *visits.Counter
+	domains    *domains.Cache
 }
 
 type HandlerParams struct {
@@ -35,6 +42,9 @@ type HandlerParams struct {
 	CookieName   string
 	CookiePath   string
 	ServerSecret string
+	Resolver     domains.Resolver
+	PageCache    *pagecache.Cache
+	Moderation   *moderation.Filter
 }
 
 type gzipResponseWriter struct {
@@ -78,11 +88,24 @@ func New(params HandlerParams) *Handler {
 
 	translator := i18n.New(params.Locales).TranslateHTTPRequest
 
-	return &Handler{
+	if params.PageCache == nil {
+		params.PageCache = pagecache.New(0)
+	}
+
+	if params.Moderation == nil {
+		params.Moderation = moderation.New(nil)
+	}
+
+	h := &Handler{
 		params:     params,
 		Translator: translator,
 		Sessions:   sessions,
+		domains:    domains.NewCache(domainCacheSize, domainCacheTTL),
 	}
+
+	h.visits = visits.New(h.addVisits)
+
+	return h
 }
 
 func (h *Handler) Prod() bool {
@@ -105,6 +128,29 @@ func (h *Handler) Log() *slog.Logger {
 	return h.params.Logger
 }
 
+// Resolver returns the DNS resolver used to verify custom domains, falling
+// back to the system resolver
+func (h *Handler) Resolver() domains.Resolver {
+	if h.params.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return h.params.Resolver
+}
+
+// PageCache returns the cache of rendered public site pages
+func (h *Handler) PageCache() *pagecache.Cache {
+	return h.params.PageCache
+}
+
+func (h *Handler) Moderation() *moderation.Filter {
+	return h.params.Moderation
+}
+
+// Visits returns the buffer public site visits are counted in
+func (h *Handler) Visits() *visits.Counter {
+	return h.visits
+}
+
 func (h *Handler) SMTPClient() *smtp.Auth {
 	return smtp.Client(h.params.SMTPAuth)
 }
@@ -1,131 +1,151 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
-	"strings"
+	"net/url"
 
 	"app/config"
+	"app/cursor"
 	"app/internal/db"
 	"app/templates"
+)
 
-	"github.com/lithammer/fuzzysearch/fuzzy"
+const (
+	// sitesPerPage matches the LIMIT of the paginated site queries, a shorter
+	// page is the last one
+	sitesPerPage = 30
 )
 
 func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	sites, err := h.Queries().GetHomePageSitesWithMetricsFromMostTotalVisits(ctx)
-	if err != nil {
-		h.Log().Error("error querying sites with metrics", "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	tab := r.URL.Query().Get("tab")
+	if tab != templates.HomeTabNewest && tab != templates.HomeTabMostVisited {
+		tab = templates.HomeTabTrending
 	}
 
-	tr := h.Translator(r)
+	order := "home:" + tab
 
-	header := templates.HomeHeader(tr)
-	content := templates.CardsGrid(tr, sites, false)
+	var after *cursor.Cursor
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		c, err := cursor.Decode(h.params.ServerSecret, token, order)
+		if err != nil {
+			h.Log().Debug("invalid home cursor", "error", err, "tab", tab)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		after = &c
+	}
 
-	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
-		h.Log().Error("error rendering template", "error", err)
+	sites, err := h.homeSites(ctx, tab, after)
+	if err != nil {
+		h.Log().Error("error querying sites with metrics", "error", err, "tab", tab)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-}
 
-func (h *Handler) Terms(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	tr := h.Translator(r)
-
-	header := templates.Empty()
-	content := templates.Terms()
-
-	head := templates.SiteHead{
-		Title:       config.AppTitle + " | " + ("terms"),
-		Description: "",
-	}
-
-	if err := templates.Base(tr, header, content, &head, false).Render(ctx, w); err != nil {
-		h.Log().Error("error rendering template", "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	next := ""
+	if len(sites) == sitesPerPage {
+		last := sites[len(sites)-1]
+		token, err := cursor.Encode(h.params.ServerSecret, cursor.Cursor{
+			Order:   order,
+			Page:    nextPage(after),
+			Site:    last.SiteID,
+			Visits:  last.MetricVisitsTotal,
+			Rank:    last.MetricTrendingRank,
+			Created: last.SiteCreatedUnix,
+		})
+		if err != nil {
+			h.Log().Error("error encoding home cursor", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		next = config.Endpoints[config.RootPath] + "?" + url.Values{"tab": {tab}, "cursor": {token}}.Encode()
 	}
-}
 
-func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	tr := h.Translator(r)
-	ctx := r.Context()
-
-	jsonStr := r.URL.Query().Get("datastar")
 
-	var payload struct {
-		Query string `json:"search"`
-	}
-
-	if err := json.Unmarshal([]byte(jsonStr), &payload); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if after != nil {
+		if err := templates.CardsPage(tr, sites, nil, after.Page, next).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		return
 	}
 
-	sites, err := h.Queries().GetPublishedSitesWithMetrics(ctx)
+	cloud, err := h.Queries().GetTagCloud(ctx, tagCloudSize)
 	if err != nil {
-		h.Log().Error("error querying sites with metrics", "error", err)
+		h.Log().Error("error querying tag cloud", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	qq := strings.Fields(
-		strings.ReplaceAll(
-			strings.ToLower(strings.TrimSpace(payload.Query)),
-			",",
-			" ",
-		),
-	)
-	if len(qq) == 0 {
-		if err := templates.CardsGrid(tr, sites, false).Render(ctx, w); err != nil {
-			h.Log().Error("error rendering template", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	header := templates.HomeHeader(tr, tab, cloud)
+	content := templates.PagedCardsGrid(tr, sites, nil, next)
+
+	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+}
 
-	matched := make([]db.SitesWithMetric, 0, len(sites))
-
-	for _, s := range sites {
-		title := strings.ToLower(s.SiteTitle)
-		desc := strings.ToLower(s.SiteDescription)
-
-		type Tag struct {
-			Name  string `json:"name"`
-			Color string `json:"color"`
+// homeSites returns the page of home page sites of tab that follows after,
+// or the first one when after is nil
+func (h *Handler) homeSites(ctx context.Context, tab string, after *cursor.Cursor) ([]db.SitesWithMetric, error) {
+	switch tab {
+	case templates.HomeTabNewest:
+		if after == nil {
+			return h.Queries().GetHomePageSitesWithMetricsFromNewest(ctx)
+		}
+		return h.Queries().GetHomePageSitesWithMetricsFromNewestOlderThan(ctx, db.GetHomePageSitesWithMetricsFromNewestOlderThanParams{
+			Created: after.Created,
+			Site:    after.Site,
+		})
+	case templates.HomeTabMostVisited:
+		if after == nil {
+			return h.Queries().GetHomePageSitesWithMetricsFromMostTotalVisits(ctx)
 		}
+		return h.Queries().GetHomePageSitesWithMetricsFromMostTotalVisitsLessThan(ctx, db.GetHomePageSitesWithMetricsFromMostTotalVisitsLessThanParams{
+			Visits: after.Visits,
+			Site:   after.Site,
+		})
+	default:
+		if after == nil {
+			return h.Queries().GetHomePageSitesWithMetricsFromMostTrending(ctx)
+		}
+		return h.Queries().GetHomePageSitesWithMetricsFromMostTrendingAfter(ctx, db.GetHomePageSitesWithMetricsFromMostTrendingAfterParams{
+			Rank: after.Rank,
+			Site: after.Site,
+		})
+	}
+}
 
-		var tagsArr []Tag
-		_ = json.Unmarshal([]byte(s.SiteTagsJson), &tagsArr)
+// nextPage returns the load more slot the page after the one requested with
+// after is rendered into
+func nextPage(after *cursor.Cursor) int {
+	if after == nil {
+		return 1
+	}
+	return after.Page + 1
+}
 
-		var tagNames []string
-		for _, t := range tagsArr {
-			tagNames = append(tagNames, t.Name)
-		}
+func (h *Handler) Terms(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-		tags := strings.ToLower(strings.Join(tagNames, " "))
+	tr := h.Translator(r)
 
-		siteStr := title + " " + desc + " " + tags
+	header := templates.Empty()
+	content := templates.Terms()
 
-		for _, q := range qq {
-			if fuzzy.MatchNormalized(q, siteStr) {
-				matched = append(matched, s)
-				break
-			}
-		}
+	head := templates.SiteHead{
+		Title:       config.AppTitle + " | " + ("terms"),
+		Description: "",
 	}
 
-	h.Log().Debug("sites with metric matched", "count", len(matched), "sites", matched)
-
-	if err := templates.CardsGrid(tr, matched, false).Render(ctx, w); err != nil {
+	if err := templates.Base(tr, header, content, &head, false).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
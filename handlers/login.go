@@ -27,7 +27,17 @@ type ctxKey string
 
 const ctxSessionKey ctxKey = "session"
 
+// Purposes of one time passwords, a token is only accepted by the flow that
+// issued it
+const (
+	otpRegister = "register"
+	otpLogin    = "login"
+	otpEmail    = "email"
+	otpReport   = "report"
+)
+
 type key struct {
+	purpose     string
 	hashedEmail string
 	otp         string
 	expires     time.Time
@@ -92,7 +102,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.issueOTP(h.Translator(r), email)
+	token, err := h.issueOTP(h.Translator(r), email, otpRegister)
 	if err != nil {
 		h.Log().Error("error issuing otp", "error", err)
 		templates.Notice(
@@ -139,7 +149,7 @@ func (h *Handler) RegisterConfirm(w http.ResponseWriter, r *http.Request) {
 
 	otp := r.FormValue("otp")
 
-	if err := h.verifyOTP(key, email, token, otp); err != nil {
+	if err := h.verifyOTP(key, otpRegister, email, token, otp); err != nil {
 		templates.Notice(
 			templates.RegisterNoticeID,
 			templates.NoticeWarn,
@@ -310,7 +320,7 @@ func (h *Handler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.issueOTP(tr, req.Email)
+	token, err := h.issueOTP(tr, req.Email, otpEmail)
 	if err != nil {
 		h.Log().Error("error issuing otp", "error", err)
 		templates.Notice(
@@ -369,7 +379,22 @@ func (h *Handler) DeleteSite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Queries().DeleteSite(ctx, site.SiteID); err != nil {
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		templates.Notice(
+			templates.EditorDeleteSiteNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.DeleteSite(ctx, site.SiteID); err != nil {
 		h.Log().Error("error deleting site", "error", err)
 		templates.Notice(
 			templates.AccountDeleteNoticeID,
@@ -380,6 +405,31 @@ func (h *Handler) DeleteSite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditSiteDelete,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "title": site.SiteTitle},
+	}); err != nil {
+		h.Log().Error("error auditing site deletion", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		templates.Notice(
+			templates.EditorDeleteSiteNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
 	if err := templates.Redirect(config.Endpoints[config.DashboardPath]).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
 		return
@@ -486,6 +536,28 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	slugs := make([]string, 0, len(sites))
+	for _, site := range sites {
+		slugs = append(slugs, site.SiteSlug)
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditAccountDelete,
+		targetType: auditTargetUser,
+		target:     session.SessionUser,
+		payload:    map[string]any{"email": user.UserEmail, "sites": slugs},
+	}); err != nil {
+		h.Log().Error("error auditing account deletion", "error", err)
+		templates.Notice(
+			templates.AccountDeleteNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		h.Log().Error("error deleting account", "error", err)
 		templates.Notice(
@@ -497,6 +569,10 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, site := range sites {
+		h.PageCache().Invalidate(site.SiteSlug)
+	}
+
 	h.Logout(w, r)
 
 	if err := templates.Redirect(config.Endpoints[config.RootPath]).Render(ctx, w); err != nil {
@@ -590,7 +666,7 @@ func (h *Handler) ChangeEmailConfirm(w http.ResponseWriter, r *http.Request) {
 	}
 	key := val.(key)
 
-	if err := h.verifyOTP(key, req.Email, req.Token, req.OTP); err != nil {
+	if err := h.verifyOTP(key, otpEmail, req.Email, req.Token, req.OTP); err != nil {
 		templates.Notice(
 			templates.ChangeEmailNoticeID,
 			templates.NoticeWarn,
@@ -601,7 +677,9 @@ func (h *Handler) ChangeEmailConfirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Queries().UpdateUser(ctx, db.UpdateUserParams{
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdateUser(ctx, db.UpdateUserParams{
 		UserEmail:        req.Email,
 		UserModifiedUnix: time.Now().Unix(),
 		UserID:           session.SessionUser,
@@ -616,6 +694,23 @@ func (h *Handler) ChangeEmailConfirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditEmailChange,
+		targetType: auditTargetUser,
+		target:     session.SessionUser,
+		payload:    map[string]any{"old_email": user.UserEmail, "new_email": req.Email},
+	}); err != nil {
+		h.Log().Error("error auditing email change", "error", err)
+		templates.Notice(
+			templates.ChangeEmailNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		h.Log().Error("error updating user", "error", err)
 		templates.Notice(
@@ -698,7 +793,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.issueOTP(h.Translator(r), email)
+	token, err := h.issueOTP(h.Translator(r), email, otpLogin)
 	if err != nil {
 		h.Log().Error("error issuing otp", "error", err)
 		templates.Notice(
@@ -763,7 +858,10 @@ func (h *Handler) LogoutAskedSession(w http.ResponseWriter, r *http.Request) {
 	var index int
 	for id, s := range sessions {
 		if s.SessionID == askedSession {
-			h.Queries().DeleteSession(ctx, askedSession)
+			if err := h.revokeSession(r, session.SessionUser, s); err != nil {
+				h.Log().Error("error deleting session", "error", err, "sessionID", askedSession)
+				return
+			}
 			index = id
 			h.Log().Debug("session deleted", "sessionID", askedSession)
 			break
@@ -780,6 +878,35 @@ func (h *Handler) LogoutAskedSession(w http.ResponseWriter, r *http.Request) {
 	templates.SessionsTable(h.Translator(r), session, sessions).Render(ctx, w)
 }
 
+// revokeSession deletes a session of user on request of that same user
+func (h *Handler) revokeSession(r *http.Request, user int64, session db.Session) error {
+	ctx := r.Context()
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.DeleteSession(ctx, session.SessionID); err != nil {
+		return err
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      user,
+		action:     auditSessionRevoke,
+		targetType: auditTargetSession,
+		target:     session.SessionID,
+		payload:    map[string]any{"device": session.SessionDevice},
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (h *Handler) LoginConfirm(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -809,7 +936,7 @@ func (h *Handler) LoginConfirm(w http.ResponseWriter, r *http.Request) {
 
 	otp := r.FormValue("otp")
 
-	if err := h.verifyOTP(key, email, token, otp); err != nil {
+	if err := h.verifyOTP(key, otpLogin, email, token, otp); err != nil {
 		templates.Notice(
 			templates.LoginNoticeID,
 			templates.NoticeWarn,
@@ -946,7 +1073,7 @@ func (h *Handler) verifyClient(w http.ResponseWriter, r *http.Request, enforceCS
 	return session, nil
 }
 
-func (h *Handler) issueOTP(tr func(string) string, email string) (string, error) {
+func (h *Handler) issueOTP(tr func(string) string, email, purpose string) (string, error) {
 	hashedEmailBytes, err := bcrypt.GenerateFromPassword([]byte(email), bcrypt.DefaultCost)
 	hashedEmail := string(hashedEmailBytes)
 	if err != nil {
@@ -959,6 +1086,7 @@ func (h *Handler) issueOTP(tr func(string) string, email string) (string, error)
 	}
 
 	key := key{
+		purpose:     purpose,
 		hashedEmail: hashedEmail,
 		otp:         otp,
 		expires:     time.Now().Add(5 * time.Minute),
@@ -996,7 +1124,12 @@ func (h *Handler) issueOTP(tr func(string) string, email string) (string, error)
 	return token, nil
 }
 
-func (h *Handler) verifyOTP(key key, email, token, otp string) error {
+func (h *Handler) verifyOTP(key key, purpose, email, token, otp string) error {
+	if key.purpose != purpose {
+		h.Log().Debug("otp issued for another purpose", "expected", purpose, "got", key.purpose)
+		return fmt.Errorf("invalid otp purpose")
+	}
+
 	if time.Now().After(key.expires) {
 		keys.Delete(token)
 		h.Log().Debug("token expired", "token", token)
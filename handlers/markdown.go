@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"app/database"
+	"app/editorjs"
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+)
+
+// ImportMarkdown converts an uploaded Markdown file into the site content. It
+// is stored as the sync data and the draft, so the editor loads it next time
+// whether the owner plan syncs or not
+func (h *Handler) ImportMarkdown(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMemory)
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		h.Log().Debug("invalid markdown upload", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile(templates.MarkdownUploadName)
+	if err != nil {
+		h.Log().Debug("missing markdown file", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext != ".md" && ext != ".markdown" && ext != ".txt" {
+		templates.Notice(
+			templates.MarkdownNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("markdown_invalid_file"),
+		).Render(ctx, w)
+		return
+	}
+
+	src, err := io.ReadAll(file)
+	if err != nil {
+		h.Log().Debug("error reading markdown file", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	content, err := json.Marshal(editorjs.FromMarkdown(string(src)))
+	if err != nil {
+		h.Log().Error("error marshalling imported content", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	contentGz, err := utils.Gzip(content)
+	if err != nil {
+		h.Log().Error("error gzip imported content", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(contentGz) > MaxHTMLSize {
+		templates.Notice(
+			templates.MarkdownNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("publish_too_large"),
+		).Render(ctx, w)
+		return
+	}
+
+	title := site.SiteTitle
+	description := site.SiteDescription
+
+	if draft, err := h.Queries().GetDraft(ctx, site.SiteID); err == nil {
+		title = draft.DraftTitle
+		description = draft.DraftDescription
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := putSyncData(ctx, qtx, site.SiteID, database.SiteData{
+		Title:       title,
+		Description: description,
+		LastUpdated: time.Now().UnixMilli(),
+		Content:     json.RawMessage(content),
+	}); err != nil {
+		h.Log().Error("error storing imported sync data", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := qtx.UpsertDraft(ctx, db.UpsertDraftParams{
+		DraftSite:         site.SiteID,
+		DraftTitle:        title,
+		DraftDescription:  description,
+		DraftContentGz:    contentGz,
+		DraftModifiedUnix: time.Now().Unix(),
+	}); err != nil {
+		h.Log().Error("error storing imported draft", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.Log().Debug("imported markdown", "site_id", site.SiteID, "bytes", len(src))
+
+	if err := templates.MarkdownImported(site.SiteSlug).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// ExportMarkdown downloads the published content of a site as Markdown
+func (h *Handler) ExportMarkdown(w http.ResponseWriter, r *http.Request) {
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + site.SiteTitle + "\n\n")
+	if site.SiteDescription != "" {
+		b.WriteString(site.SiteDescription + "\n\n")
+	}
+
+	if len(site.SiteContentGz) > 0 {
+		content, err := utils.Gunzip(site.SiteContentGz)
+		if err != nil {
+			h.Log().Error("error gunzip site content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		doc, err := editorjs.Parse(content)
+		if err != nil {
+			h.Log().Error("error parsing site content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		md, err := editorjs.ToMarkdown(doc)
+		if err != nil {
+			h.Log().Error("error converting site content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		b.WriteString(md)
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+site.SiteSlug+`.md"`)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		h.Log().Error("error writing markdown", "error", err)
+		return
+	}
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/database"
+	"app/internal/db"
+	"app/moderation"
+	"app/templates"
+)
+
+// siteFlag is a field of a site that matched a term of the wordlists
+type siteFlag struct {
+	field string
+	moderation.Result
+}
+
+// moderate checks the fields of a site, keyed by name, against the
+// wordlists. It returns the fields that matched with the most severe verdict
+// first, so content is rejected when the first one is moderation.Reject
+func (h *Handler) moderate(fields map[string]string) []siteFlag {
+	var matches []siteFlag
+
+	for field, text := range fields {
+		if r := h.Moderation().Check(text); r.Verdict != moderation.Allow {
+			matches = append(matches, siteFlag{field: field, Result: r})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b siteFlag) int {
+		return cmp.Or(cmp.Compare(b.Verdict, a.Verdict), strings.Compare(a.field, b.field))
+	})
+
+	return matches
+}
+
+func rejected(matches []siteFlag) bool {
+	return len(matches) > 0 && matches[0].Verdict == moderation.Reject
+}
+
+// flagSite queues a site for review for each flagged field, fields that
+// already have an open flag for the same term are skipped
+func flagSite(ctx context.Context, queries *db.Queries, siteID int64, flags []siteFlag) error {
+	now := time.Now().Unix()
+
+	for _, f := range flags {
+		if f.Verdict != moderation.Flag {
+			continue
+		}
+
+		if err := queries.InsertSiteFlag(ctx, db.InsertSiteFlagParams{
+			FlagSite:        siteID,
+			FlagField:       f.field,
+			FlagTerm:        truncate(f.Term, 63),
+			FlagLang:        f.Lang,
+			FlagCreatedUnix: now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isModerator reports whether user can review reports and take sites down,
+// admins are moderators too
+func (h *Handler) isModerator(ctx context.Context, user int64) bool {
+	u, err := h.Queries().GetUserByID(ctx, user)
+	if err != nil {
+		h.Log().Debug("error querying user", "error", err)
+		return false
+	}
+	return u.UserRole == database.RoleModerator || u.UserRole == database.RoleAdmin
+}
+
+// moderatorSession returns the session of the request when it belongs to a
+// moderator
+func (h *Handler) moderatorSession(r *http.Request) (db.Session, bool) {
+	session, ok := r.Context().Value(ctxSessionKey).(db.Session)
+	if !ok || !h.isModerator(r.Context(), session.SessionUser) {
+		return db.Session{}, false
+	}
+	return session, true
+}
+
+func (h *Handler) ModerationPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	if _, ok := h.moderatorSession(r); !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	reports, flags, err := h.moderationQueue(ctx)
+	if err != nil {
+		h.Log().Error("error loading moderation queue", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	header := templates.ModerationHeader(tr)
+	content := templates.ModerationQueue(tr, reports, flags)
+
+	if err := templates.Base(tr, header, content, nil, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// moderationQueue returns the open reports and flags, oldest first
+func (h *Handler) moderationQueue(ctx context.Context) ([]db.GetOpenSiteReportsRow, []db.GetOpenSiteFlagsRow, error) {
+	reports, err := h.Queries().GetOpenSiteReports(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flags, err := h.Queries().GetOpenSiteFlags(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reports, flags, nil
+}
+
+func (h *Handler) renderModerationQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reports, flags, err := h.moderationQueue(ctx)
+	if err != nil {
+		h.Log().Error("error loading moderation queue", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.ModerationQueue(h.Translator(r), reports, flags).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Takedown unpublishes a site with a reason for its owner, who is notified
+// in the dashboard and by email, and resolves the reports and flags of it
+func (h *Handler) Takedown(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	session, ok := h.moderatorSession(r)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"takedown_reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid takedown request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > reportDetailsMax {
+		templates.Notice(
+			templates.ModerationNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("takedown_reason_required"),
+		).Render(ctx, w)
+		return
+	}
+
+	site, err := h.Queries().GetSiteBySlug(ctx, r.PathValue("site"))
+	if err != nil {
+		h.Log().Debug("error querying site", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := h.takedown(r, site, session.SessionUser, req.Reason); err != nil {
+		h.Log().Error("error taking site down", "site", site.SiteSlug, "error", err)
+		templates.Notice(
+			templates.ModerationNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.renderModerationQueue(w, r)
+}
+
+// DismissReport resolves a report without acting on the site
+func (h *Handler) DismissReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if _, ok := h.moderatorSession(r); !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	reportID, err := strconv.ParseInt(r.PathValue("report"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.Queries().ResolveSiteReport(ctx, db.ResolveSiteReportParams{
+		ReportResolvedUnix: time.Now().Unix(),
+		ReportID:           reportID,
+	}); err != nil {
+		h.Log().Error("error resolving report", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.renderModerationQueue(w, r)
+}
+
+// takedown unpublishes site on behalf of moderator, resolves its reports and
+// flags and emails the owner the reason
+func (h *Handler) takedown(r *http.Request, site db.Site, moderator int64, reason string) error {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	owner, err := h.Queries().GetUserByID(ctx, site.SiteUser)
+	if err != nil {
+		return err
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	now := time.Now().Unix()
+
+	if err := qtx.TakedownSite(ctx, db.TakedownSiteParams{
+		SiteModifiedUnix: now,
+		SiteID:           site.SiteID,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.InsertTakedown(ctx, db.InsertTakedownParams{
+		TakedownSite:          site.SiteID,
+		TakedownModerator:     moderator,
+		TakedownReason:        reason,
+		TakedownCreatedUnix:   now,
+		TakedownSitePublished: site.SitePublished,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.ResolveSiteReports(ctx, db.ResolveSiteReportsParams{
+		ReportResolvedUnix: now,
+		ReportSite:         site.SiteID,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.ResolveSiteFlags(ctx, db.ResolveSiteFlagsParams{
+		FlagResolvedUnix: now,
+		FlagSite:         site.SiteID,
+	}); err != nil {
+		return err
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      moderator,
+		action:     auditSiteTakedown,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "owner": site.SiteUser, "reason": reason},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Info("site taken down", "site", site.SiteSlug, "moderator", moderator)
+
+	subject := tr("takedown_email_subject")
+	body := tr("takedown_email_body") + " " + site.SiteSlug + "\n\n" + reason
+
+	if h.Prod() {
+		if err := h.SMTPClient().SendText(
+			config.ServerSMTPUser,
+			[]string{owner.UserEmail},
+			subject,
+			body,
+		); err != nil {
+			h.Log().Error("error sending takedown email", "error", err)
+		}
+	} else {
+		h.Log().Debug(
+			"sent takedown email",
+			"from", config.ServerSMTPUser,
+			"to", owner.UserEmail,
+			"subject", subject,
+			"body", body,
+		)
+	}
+
+	return nil
+}
+
+// takenDown reports whether site has a takedown that has not been lifted,
+// such sites cannot be published by their owner
+func (h *Handler) takenDown(ctx context.Context, site int64) (bool, error) {
+	n, err := h.Queries().CountActiveTakedowns(ctx, site)
+	return n > 0, err
+}
@@ -0,0 +1,593 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/database"
+	"app/editorjs"
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+)
+
+const (
+	freePagesLimit int64 = 2
+	paidPagesLimit int64 = 20
+)
+
+// pagesLimit returns how many pages a site can have besides its home page,
+// based on the owner plan
+func (h *Handler) pagesLimit(ctx context.Context, user int64) (int64, error) {
+	plan, err := h.Queries().GetPlan(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	if plan.UserPlanActive != 1 || time.Now().Unix() > plan.UserPlanDueUnix {
+		return freePagesLimit, nil
+	}
+
+	return paidPagesLimit, nil
+}
+
+// parsePageSlug validates a page path, each segment must be a valid endpoint.
+// Custom domains serve pages at the root, so the first segment cannot be the
+// name of an app endpoint
+func parsePageSlug(raw string) (string, error) {
+	raw = strings.Trim(strings.TrimSpace(raw), "/")
+
+	var segments []string
+	for segment := range strings.SplitSeq(raw, "/") {
+		parsed, err := parseEndpoint(segment)
+		if err != nil {
+			return "", err
+		}
+		segments = append(segments, parsed)
+	}
+
+	for _, e := range config.Endpoints {
+		used := strings.TrimPrefix(e, config.Endpoints[config.RootPath])
+		used, _, _ = strings.Cut(used, "/")
+
+		if used != "" && used == segments[0] {
+			return "", errors.New("page slug cannot start with an app endpoint")
+		}
+	}
+
+	slug := strings.Join(segments, "/")
+	if len(slug) > 63 {
+		return "", errors.New("page slug cannot be more than 63 characters long")
+	}
+
+	return slug, nil
+}
+
+// renderPages renders the pages section of a site settings
+func (h *Handler) renderPages(w http.ResponseWriter, r *http.Request, site db.Site) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	pages, err := h.Queries().GetPagesBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying pages", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Pages(tr, site.SiteSlug, pages).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) NewPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		Slug  string `json:"page_slug"`
+		Title string `json:"page_title"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid new page request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	slug, err := parsePageSlug(req.Slug)
+	if err != nil {
+		h.Log().Debug("invalid page slug", "slug", req.Slug, "error", err)
+		templates.Notice(
+			templates.PagesNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("dashboard_invalid_slug"),
+		).Render(ctx, w)
+		return
+	}
+
+	if req.Title == "" || len(req.Title) > 63 {
+		templates.Notice(
+			templates.PagesNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("editor_empty_title"),
+		).Render(ctx, w)
+		return
+	}
+
+	limit, err := h.pagesLimit(ctx, site.SiteUser)
+	if err != nil {
+		h.Log().Error("error querying plan", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	// Pages of a site are created one at a time, so concurrent requests can
+	// neither go over the limit nor take the same position
+	if err := qtx.LockSitePages(ctx, site.SiteID); err != nil {
+		h.Log().Error("error locking site", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	count, err := qtx.CountPagesBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error counting pages", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if count+1 > limit {
+		h.Log().Debug("site reached max pages", "site_id", site.SiteID)
+		msg := "pages_maximum_reached"
+		if limit == freePagesLimit {
+			msg = "pages_upgrade_to_create_more"
+		}
+		templates.Notice(
+			templates.PagesNoticeID,
+			templates.NoticeInfo,
+			tr("info"),
+			tr(msg),
+		).Render(ctx, w)
+		return
+	}
+
+	position, err := qtx.GetNextPagePosition(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying page position", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().Unix()
+
+	if _, err := qtx.InsertPage(ctx, db.InsertPageParams{
+		PageSite:         site.SiteID,
+		PageSlug:         slug,
+		PageTitle:        req.Title,
+		PageHtmlGz:       []byte{},
+		PageContentGz:    []byte{},
+		PagePosition:     position,
+		PageCreatedUnix:  now,
+		PageModifiedUnix: now,
+	}); err != nil {
+		h.Log().Debug("error inserting page", "error", err, "slug", slug)
+		templates.Notice(
+			templates.PagesNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("dashboard_slug_not_available"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Redirect(config.Endpoints[config.EditorPath]+site.SiteSlug+"/"+slug).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) PageEditor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	page, err := h.Queries().GetPage(ctx, db.GetPageParams{
+		PageSite: site.SiteID,
+		PageSlug: r.PathValue("page"),
+	})
+	if err != nil {
+		h.Log().Debug("error loading page", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	initial := database.SiteData{
+		Title: page.PageTitle,
+	}
+
+	if len(page.PageContentGz) > 0 {
+		content, err := utils.Gunzip(page.PageContentGz)
+		if err != nil {
+			h.Log().Error("error gunzip page content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		initial.Content = json.RawMessage(content)
+	}
+
+	tr := h.Translator(r)
+
+	pageURL := utils.HostURL(r) + config.Endpoints[config.RootPath] + site.SiteSlug + "/" + page.PageSlug
+
+	header := templates.PageEditorHeader(tr, site, page)
+	content := templates.PageEditor(tr, site, page, pageURL, initial)
+
+	templates.Base(tr, header, content, nil, true).Render(ctx, w)
+}
+
+func (h *Handler) PublishPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	page, err := h.Queries().GetPage(ctx, db.GetPageParams{
+		PageSite: site.SiteID,
+		PageSlug: r.PathValue("page"),
+	})
+	if err != nil {
+		h.Log().Debug("error loading page", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if down, err := h.takenDown(ctx, site.SiteID); err != nil || down {
+		h.Log().Debug("cannot publish page of site taken down", "site", site.SiteSlug, "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("takedown_cannot_publish"),
+		).Render(ctx, w)
+		return
+	}
+
+	var data PublishData
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.Log().Debug("invalid publish page request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if data.Title == "" {
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("editor_empty_title"),
+		).Render(ctx, w)
+		return
+	}
+
+	rendered, err := editorjs.RenderJSON([]byte(data.Content))
+	if err != nil {
+		h.Log().Debug("invalid editor content", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	sanitized := database.SanitizeHTML(rendered)
+
+	htmlGz, err := utils.Gzip([]byte(sanitized))
+	if err != nil {
+		h.Log().Debug("failed to gzip html", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	contentGz, err := utils.Gzip([]byte(data.Content))
+	if err != nil {
+		h.Log().Debug("failed to gzip content", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(data.Title) > 63 || len(htmlGz) > MaxHTMLSize || len(contentGz) > MaxHTMLSize {
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("publish_too_large"),
+		).Render(ctx, w)
+		return
+	}
+
+	matches := h.moderate(map[string]string{
+		"page_title":   data.Title,
+		"page_content": database.PlainText(sanitized),
+	})
+	if rejected(matches) {
+		h.Log().Debug("rejected page content", "site", site.SiteSlug, "field", matches[0].field, "term", matches[0].Term)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("moderation_rejected"),
+		).Render(ctx, w)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdatePage(ctx, db.UpdatePageParams{
+		PageTitle:        data.Title,
+		PageHtmlGz:       htmlGz,
+		PageContentGz:    contentGz,
+		PagePublished:    1,
+		PageModifiedUnix: time.Now().Unix(),
+		PageID:           page.PageID,
+	}); err != nil {
+		h.Log().Error("error updating page", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := flagSite(ctx, qtx, site.SiteID, matches); err != nil {
+		h.Log().Error("error flagging site", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      site.SiteUser,
+		action:     auditPagePublish,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "page": page.PageSlug, "title": data.Title},
+	}); err != nil {
+		h.Log().Error("error auditing page publishing", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("error commit tx", "error", err)
+		templates.Notice(
+			templates.PublishNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Debug("published page", "site_id", site.SiteID, "page_id", page.PageID)
+
+	if err := templates.Notice(
+		templates.PublishNoticeID,
+		templates.NoticeInfo,
+		tr("success"),
+		tr("pages_published"),
+	).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MovePage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		Direction string `json:"page_direction"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid move page request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	pages, err := h.Queries().GetPagesBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying pages", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	i := slices.IndexFunc(pages, func(p db.GetPagesBySiteRow) bool {
+		return p.PageSlug == r.PathValue("page")
+	})
+	if i < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	j := i
+	switch req.Direction {
+	case "up":
+		j = i - 1
+	case "down":
+		j = i + 1
+	}
+
+	if j < 0 || j >= len(pages) || j == i {
+		h.renderPages(w, r, site)
+		return
+	}
+
+	pages[i], pages[j] = pages[j], pages[i]
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	// Rewrite every position so they stay contiguous
+	for position, p := range pages {
+		if err := qtx.UpdatePagePosition(ctx, db.UpdatePagePositionParams{
+			PagePosition: int64(position),
+			PageID:       p.PageID,
+		}); err != nil {
+			h.Log().Error("error updating page position", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.renderPages(w, r, site)
+}
+
+func (h *Handler) DeletePage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.LockSitePages(ctx, site.SiteID); err != nil {
+		h.Log().Error("error locking site", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := qtx.DeletePage(ctx, db.DeletePageParams{
+		PageSite: site.SiteID,
+		PageSlug: r.PathValue("page"),
+	}); err != nil {
+		h.Log().Error("error deleting page", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Positions stay contiguous, the way reordering leaves them
+	if err := qtx.CompactPagePositions(ctx, site.SiteID); err != nil {
+		h.Log().Error("error compacting page positions", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Debug("deleted page", "site_id", site.SiteID, "page", r.PathValue("page"))
+
+	h.renderPages(w, r, site)
+}
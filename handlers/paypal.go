@@ -341,8 +341,20 @@ func (h *Handler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
 		h.Log().Error("failed to complete order", "error", err)
 	}
 
-	if err := h.Queries().UpdatePlan(ctx, db.UpdatePlanParams{
-		UserPlanDueUnix:      now.Add(24 * time.Hour * 365).Unix(),
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		http.Error(w, "failed to update plan", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	due := now.Add(24 * time.Hour * 365).Unix()
+
+	if err := qtx.UpdatePlan(ctx, db.UpdatePlanParams{
+		UserPlanDueUnix:      due,
 		UserPlanModifiedUnix: now.Unix(),
 		UserPlanActive:       1,
 		UserPlanID:           plan.UserPlanID,
@@ -352,6 +364,30 @@ func (h *Handler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := audit(ctx, qtx, r, auditEvent{
+		actor:      session.SessionUser,
+		action:     auditPlanChange,
+		targetType: auditTargetUser,
+		target:     session.SessionUser,
+		payload: map[string]any{
+			"order":      resp.ID,
+			"status":     resp.Status,
+			"due":        due,
+			"active":     1,
+			"due_before": plan.UserPlanDueUnix,
+		},
+	}); err != nil {
+		h.Log().Error("failed to audit plan change", "error", err)
+		http.Error(w, "failed to update plan", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("failed to update plan", "error", err)
+		http.Error(w, "failed to update plan", http.StatusInternalServerError)
+		return
+	}
+
 	minResp := CompleteOrderResponse{
 		ID: resp.ID,
 	}
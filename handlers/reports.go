@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"app/config"
+	"app/internal/db"
+	"app/ratelimit"
+	"app/templates"
+	"app/utils"
+)
+
+const (
+	// reportsPerHour is how many reports, and how many attempts at verifying
+	// a reporter email, a client can send per hour
+	reportsPerHour = 5
+
+	// reportEmailsPerHour is how many verification emails an address gets
+	// per hour, and reportEmailsTotalPerHour how many are sent to anyone, so
+	// reports cannot be used to flood inboxes
+	reportEmailsPerHour      = 3
+	reportEmailsTotalPerHour = 100
+
+	reportDetailsMax = 1000
+)
+
+var (
+	reportLimiter  = ratelimit.New(reportsPerHour, time.Hour)
+	confirmLimiter = ratelimit.New(reportsPerHour, time.Hour)
+
+	reportEmailLimiter  = ratelimit.New(reportEmailsPerHour, time.Hour)
+	reportEmailsLimiter = ratelimit.New(reportEmailsTotalPerHour, time.Hour)
+
+	// pendingReports are the reports waiting for their reporter to verify
+	// an email, by the otp token sent to it
+	pendingReports sync.Map // map[string]int64
+)
+
+// PruneReports forgets the clients whose rate limit window is over and the
+// reports whose email can no longer be verified. It is meant to be run
+// periodically by the scheduler
+func (h *Handler) PruneReports(ctx context.Context) error {
+	reportLimiter.Prune()
+	confirmLimiter.Prune()
+	reportEmailLimiter.Prune()
+	reportEmailsLimiter.Prune()
+
+	now := time.Now()
+	pendingReports.Range(func(token, _ any) bool {
+		if k, ok := keys.Load(token); !ok || now.After(k.(key).expires) {
+			pendingReports.Delete(token)
+		}
+		return true
+	})
+
+	return nil
+}
+
+// reporterHash identifies the client that sent a report without storing its
+// address
+func (h *Handler) reporterHash(r *http.Request) string {
+	mac := hmac.New(sha256.New, []byte(h.params.ServerSecret))
+	mac.Write([]byte("report:" + utils.ClientIP(r)))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// reportEmailHash identifies the address a report verification is sent to
+// without keeping it in memory
+func (h *Handler) reportEmailHash(email string) string {
+	mac := hmac.New(sha256.New, []byte(h.params.ServerSecret))
+	mac.Write([]byte("report_email:" + strings.ToLower(email)))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (h *Handler) ReportForm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, err := h.Queries().GetPublishedSiteWithMetricsBySlug(ctx, r.PathValue("site"))
+	if err != nil {
+		h.Log().Debug("cannot find published site to report", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		if err := templates.NotFound(tr).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+		}
+		return
+	}
+
+	header := templates.ReportHeader(tr, site)
+	content := templates.ReportForm(tr, site.SiteSlug)
+
+	head := templates.SiteHead{
+		Title:       config.AppTitle + " | " + tr("report_site"),
+		Description: tr("report_description"),
+	}
+
+	if err := templates.Base(tr, header, content, &head, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	var req struct {
+		Reason  string `json:"report_reason"`
+		Details string `json:"report_details"`
+		Email   string `json:"report_email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid report request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Details = strings.TrimSpace(req.Details)
+	req.Email = strings.TrimSpace(req.Email)
+
+	if !slices.Contains(templates.ReportReasons, req.Reason) || req.Details == "" || len(req.Details) > reportDetailsMax {
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("report_invalid"),
+		).Render(ctx, w)
+		return
+	}
+
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil || len(req.Email) > 63 {
+			templates.Notice(
+				templates.ReportNoticeID,
+				templates.NoticeWarn,
+				tr("warn"),
+				tr("invalid_email"),
+			).Render(ctx, w)
+			return
+		}
+	}
+
+	reporter := h.reporterHash(r)
+
+	if !reportLimiter.Allow(reporter) {
+		h.Log().Debug("too many reports", "reporter", reporter)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("report_rate_limited"),
+		).Render(ctx, w)
+		return
+	}
+
+	// Checked before the report is stored, the reporter can send it again
+	// without an email
+	if req.Email != "" && (!reportEmailLimiter.Allow(h.reportEmailHash(req.Email)) || !reportEmailsLimiter.Allow("")) {
+		h.Log().Info("too many report verification emails", "reporter", reporter)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("report_rate_limited"),
+		).Render(ctx, w)
+		return
+	}
+
+	site, err := h.Queries().GetPublishedSiteWithMetricsBySlug(ctx, r.PathValue("site"))
+	if err != nil {
+		h.Log().Debug("cannot find published site to report", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	reportID, err := h.Queries().InsertSiteReport(ctx, db.InsertSiteReportParams{
+		ReportSite:        site.SiteID,
+		ReportReason:      req.Reason,
+		ReportDetails:     req.Details,
+		ReportIpHash:      reporter,
+		ReportCreatedUnix: time.Now().Unix(),
+	})
+	if err != nil {
+		h.Log().Error("error inserting report", "error", err)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.Log().Info("site reported", "site", site.SiteSlug, "reason", req.Reason, "report_id", reportID)
+
+	// Reports are stored right away, the reporter email is only attached to
+	// the report once it is verified
+	if req.Email == "" {
+		if err := templates.ReportSent(tr).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+		}
+		return
+	}
+
+	token, err := h.issueOTP(tr, req.Email, otpReport)
+	if err != nil {
+		h.Log().Error("error issuing otp", "error", err)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	pendingReports.Store(token, reportID)
+
+	if err := templates.ReportConfirm(tr, site.SiteSlug, token).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+	}
+}
+
+func (h *Handler) ReportConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	var req struct {
+		Email string `json:"report_email"`
+		OTP   string `json:"otp"`
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid report confirm request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !confirmLimiter.Allow(h.reporterHash(r)) {
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("report_rate_limited"),
+		).Render(ctx, w)
+		return
+	}
+
+	val, ok := keys.Load(req.Token)
+	if !ok {
+		h.Log().Debug("invalid token", "token", req.Token)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verifyOTP(val.(key), otpReport, strings.TrimSpace(req.Email), req.Token, req.OTP); err != nil {
+		h.Log().Debug("failed to verify otp", "error", err)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("invalid_otp"),
+		).Render(ctx, w)
+		return
+	}
+
+	reportID, ok := pendingReports.LoadAndDelete(req.Token)
+	if !ok {
+		h.Log().Debug("no pending report for token", "token", req.Token)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Queries().UpdateSiteReportEmail(ctx, db.UpdateSiteReportEmailParams{
+		ReportEmail: strings.TrimSpace(req.Email),
+		ReportID:    reportID.(int64),
+	}); err != nil {
+		h.Log().Error("error updating report email", "error", err)
+		templates.Notice(
+			templates.ReportNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := templates.ReportSent(tr).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+	}
+}
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app/config"
+	"app/database"
+	"app/editorjs"
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+	"app/utils/diff"
+)
+
+const (
+	freeRevisionsLimit int64 = 3
+	paidRevisionsLimit int64 = 30
+
+	restoreTargetPublished = "published"
+	restoreTargetDraft     = "draft"
+)
+
+// revisionsLimit returns how many revisions are kept for a user, based on
+// their plan
+func (h *Handler) revisionsLimit(ctx context.Context, user int64) (int64, error) {
+	plan, err := h.Queries().GetPlan(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	if plan.UserPlanActive != 1 || time.Now().Unix() > plan.UserPlanDueUnix {
+		return freeRevisionsLimit, nil
+	}
+
+	return paidRevisionsLimit, nil
+}
+
+// saveRevision stores a snapshot of a site and prunes the revisions that fall
+// outside of the retention limit
+func saveRevision(ctx context.Context, qtx *db.Queries, rev db.InsertRevisionParams, keep int64) error {
+	if _, err := qtx.InsertRevision(ctx, rev); err != nil {
+		return err
+	}
+
+	return qtx.PruneRevisions(ctx, db.PruneRevisionsParams{
+		SiteID: rev.RevisionSite,
+		Keep:   keep,
+	})
+}
+
+// putSyncData overwrites the editor sync state of a site, inserting it if the
+// site was never synced
+func putSyncData(ctx context.Context, qtx *db.Queries, siteID int64, data database.SiteData) error {
+	b, err := json.Marshal(SyncRequest{LocalData: data})
+	if err != nil {
+		return err
+	}
+
+	bgz, err := utils.Gzip(b)
+	if err != nil {
+		return err
+	}
+
+	if _, err := qtx.GetSyncData(ctx, siteID); err != nil {
+		_, err := qtx.InsertSyncData(ctx, db.InsertSyncDataParams{
+			SiteSyncID:             siteID,
+			SiteSyncDataGz:         bgz,
+			SiteSyncLastUpdateUnix: data.LastUpdated,
+		})
+		return err
+	}
+
+	return qtx.UpdateSyncData(ctx, db.UpdateSyncDataParams{
+		SiteSyncID:             siteID,
+		SiteSyncDataGz:         bgz,
+		SiteSyncLastUpdateUnix: data.LastUpdated,
+	})
+}
+
+// ownedSite loads a site by the {site} path value and checks it belongs to
+// the session user
+func (h *Handler) ownedSite(r *http.Request) (db.Site, int, bool) {
+	ctx := r.Context()
+
+	session, ok := ctx.Value(ctxSessionKey).(db.Session)
+	if !ok {
+		h.Log().Debug("error retrieving session from ctx")
+		return db.Site{}, http.StatusUnauthorized, false
+	}
+
+	slug := r.PathValue("site")
+	if slug == "" {
+		h.Log().Debug("error invalid slug", "slug", slug)
+		return db.Site{}, http.StatusBadRequest, false
+	}
+
+	site, err := h.Queries().GetSiteBySlug(ctx, slug)
+	if err != nil {
+		h.Log().Error("error querying site", "error", err, "slug", slug)
+		return db.Site{}, http.StatusNotFound, false
+	}
+
+	if site.SiteUser != session.SessionUser {
+		h.Log().Debug("user does not own site", "site_user", session.SessionUser)
+		return db.Site{}, http.StatusUnauthorized, false
+	}
+
+	return site, http.StatusOK, true
+}
+
+func (h *Handler) Revisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	revisions, err := h.Queries().GetRevisionsBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error querying revisions", "error", err)
+		templates.Notice(
+			templates.RevisionsNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	limit, err := h.revisionsLimit(ctx, site.SiteUser)
+	if err != nil {
+		h.Log().Error("error querying plan", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Revisions(tr, site.SiteSlug, revisions, limit).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) RevisionsDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	jsonStr := r.URL.Query().Get("datastar")
+
+	var payload struct {
+		From string `json:"revision_from"`
+		To   string `json:"revision_to"`
+	}
+
+	if err := json.Unmarshal([]byte(jsonStr), &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fromID, errFrom := strconv.ParseInt(payload.From, 10, 64)
+	toID, errTo := strconv.ParseInt(payload.To, 10, 64)
+	if errFrom != nil || errTo != nil {
+		h.Log().Debug("invalid revisions to compare", "from", payload.From, "to", payload.To)
+		templates.Notice(
+			templates.RevisionsNoticeID,
+			templates.NoticeInfo,
+			tr("info"),
+			tr("revisions_select_two"),
+		).Render(ctx, w)
+		return
+	}
+
+	from, err := h.Queries().GetRevision(ctx, db.GetRevisionParams{
+		RevisionID:   fromID,
+		RevisionSite: site.SiteID,
+	})
+	if err != nil {
+		h.Log().Debug("error querying revision", "error", err, "revision", fromID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	to, err := h.Queries().GetRevision(ctx, db.GetRevisionParams{
+		RevisionID:   toID,
+		RevisionSite: site.SiteID,
+	})
+	if err != nil {
+		h.Log().Debug("error querying revision", "error", err, "revision", toID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	fromHTML, err := utils.Gunzip(from.RevisionHtmlGz)
+	if err != nil {
+		h.Log().Error("error gunzip revision", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	toHTML, err := utils.Gunzip(to.RevisionHtmlGz)
+	if err != nil {
+		h.Log().Error("error gunzip revision", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lines, err := diff.Lines(
+		[]string{from.RevisionTitle, from.RevisionDescription},
+		[]string{to.RevisionTitle, to.RevisionDescription},
+	)
+	if err != nil {
+		h.Log().Error("error diffing revisions", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := diff.Lines(
+		diff.Split(string(fromHTML)),
+		diff.Split(string(toHTML)),
+	)
+	if errors.Is(err, diff.ErrTooLarge) {
+		h.Log().Debug("revisions too large to diff", "from", fromID, "to", toID)
+		templates.Notice(
+			templates.RevisionsNoticeID,
+			templates.NoticeInfo,
+			tr("info"),
+			tr("revisions_too_large"),
+		).Render(ctx, w)
+		return
+	}
+	lines = append(lines, body...)
+
+	if err := templates.RevisionsDiff(tr, lines).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	revisionID, err := strconv.ParseInt(r.PathValue("revision"), 10, 64)
+	if err != nil {
+		h.Log().Debug("invalid revision", "revision", r.PathValue("revision"))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Target string `json:"revision_target"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		h.Log().Debug("invalid restore request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	revision, err := h.Queries().GetRevision(ctx, db.GetRevisionParams{
+		RevisionID:   revisionID,
+		RevisionSite: site.SiteID,
+	})
+	if err != nil {
+		h.Log().Debug("error querying revision", "error", err, "revision", revisionID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	limit, err := h.revisionsLimit(ctx, site.SiteUser)
+	if err != nil {
+		h.Log().Error("error querying plan", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	var published int64 = 1
+
+	switch payload.Target {
+	case restoreTargetPublished:
+		if down, err := h.takenDown(ctx, site.SiteID); err != nil || down {
+			h.Log().Debug("cannot restore site taken down", "site", site.SiteSlug, "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		content, err := utils.Gunzip(revision.RevisionContentGz)
+		if err != nil {
+			h.Log().Error("error gunzip revision content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Revisions are published again the same way the editor publishes,
+		// the renderer, policies and wordlists may have changed since they
+		// were saved. Revisions saved without content only have their HTML
+		var html string
+		if len(content) > 0 && string(content) != "null" {
+			html, err = editorjs.RenderJSON(content)
+			if err != nil {
+				h.Log().Debug("invalid revision content", "error", err, "revision", revisionID)
+				templates.Notice(
+					templates.RevisionsNoticeID,
+					templates.NoticeError,
+					tr("error"),
+					tr("try_later"),
+				).Render(ctx, w)
+				return
+			}
+		} else {
+			stored, err := utils.Gunzip(revision.RevisionHtmlGz)
+			if err != nil {
+				h.Log().Error("error gunzip revision", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			html = string(stored)
+		}
+
+		sanitized := database.SanitizeHTML(html)
+
+		sanitizedGz, err := utils.Gzip([]byte(sanitized))
+		if err != nil {
+			h.Log().Error("error gzip html", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if len(sanitizedGz) > MaxHTMLSize {
+			h.Log().Debug("exceeds capacity")
+			templates.Notice(
+				templates.RevisionsNoticeID,
+				templates.NoticeError,
+				tr("error"),
+				tr("publish_too_large"),
+			).Render(ctx, w)
+			return
+		}
+
+		matches := h.moderate(map[string]string{
+			"title":       revision.RevisionTitle,
+			"description": revision.RevisionDescription,
+			"content":     database.PlainText(sanitized),
+		})
+		if rejected(matches) {
+			h.Log().Debug("rejected revision content", "site", site.SiteSlug, "field", matches[0].field, "term", matches[0].Term)
+			templates.Notice(
+				templates.RevisionsNoticeID,
+				templates.NoticeWarn,
+				tr("warn"),
+				tr("moderation_rejected"),
+			).Render(ctx, w)
+			return
+		}
+
+		now := time.Now().Unix()
+
+		// Sites scheduled for a later date keep their content hidden until the
+		// scheduler publishes them
+		if site.SitePublishAtUnix > now {
+			published = 0
+		}
+
+		if err := qtx.UpdateSite(ctx, db.UpdateSiteParams{
+			SiteID:           site.SiteID,
+			SiteTitle:        revision.RevisionTitle,
+			SiteDescription:  revision.RevisionDescription,
+			SiteHtmlGz:       sanitizedGz,
+			SiteContentGz:    revision.RevisionContentGz,
+			SiteModifiedUnix: now,
+			SitePublished:    published,
+			SiteDeleted:      0,
+		}); err != nil {
+			h.Log().Error("error restoring site", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := indexSite(ctx, qtx, site.SiteID, revision.RevisionTitle, revision.RevisionDescription, sanitizedGz); err != nil {
+			h.Log().Error("error indexing site", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := flagSite(ctx, qtx, site.SiteID, matches); err != nil {
+			h.Log().Error("error flagging site", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := saveRevision(ctx, qtx, db.InsertRevisionParams{
+			RevisionSite:        site.SiteID,
+			RevisionTitle:       revision.RevisionTitle,
+			RevisionDescription: revision.RevisionDescription,
+			RevisionHtmlGz:      sanitizedGz,
+			RevisionContentGz:   revision.RevisionContentGz,
+			RevisionCreatedUnix: now,
+		}, limit); err != nil {
+			h.Log().Error("error saving revision", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := audit(ctx, qtx, r, auditEvent{
+			actor:      site.SiteUser,
+			action:     auditSitePublish,
+			targetType: auditTargetSite,
+			target:     site.SiteID,
+			payload:    map[string]any{"slug": site.SiteSlug, "title": revision.RevisionTitle, "revision": revisionID},
+		}); err != nil {
+			h.Log().Error("error auditing site publishing", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	case restoreTargetDraft:
+		content, err := utils.Gunzip(revision.RevisionContentGz)
+		if err != nil {
+			h.Log().Error("error gunzip revision content", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if len(content) == 0 {
+			content = []byte("null")
+		}
+
+		if err := qtx.UpsertDraft(ctx, db.UpsertDraftParams{
+			DraftSite:         site.SiteID,
+			DraftTitle:        revision.RevisionTitle,
+			DraftDescription:  revision.RevisionDescription,
+			DraftContentGz:    revision.RevisionContentGz,
+			DraftModifiedUnix: time.Now().Unix(),
+		}); err != nil {
+			h.Log().Error("error restoring draft", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := putSyncData(ctx, qtx, site.SiteID, database.SiteData{
+			Title:       revision.RevisionTitle,
+			Description: revision.RevisionDescription,
+			LastUpdated: time.Now().UnixMilli(),
+			Content:     json.RawMessage(content),
+		}); err != nil {
+			h.Log().Error("error restoring draft", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	default:
+		h.Log().Debug("invalid restore target", "target", payload.Target)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Debug("restored revision", "site_id", site.SiteID, "revision_id", revisionID, "target", payload.Target)
+
+	if payload.Target == restoreTargetDraft {
+		// The browser keeps its own copy of the draft, drop it so the editor
+		// picks up the restored one from the server
+		if err := templates.RestoreDraft(site.SiteSlug).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	siteURL := utils.HostURL(r) + config.Endpoints[config.RootPath] + site.SiteSlug
+
+	if err := templates.UnpublishSite(tr, site.SiteSlug, siteURL, published == 1).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Notice(
+		templates.RevisionsNoticeID,
+		templates.NoticeInfo,
+		tr("success"),
+		tr("revisions_restored"),
+	).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+)
+
+// RunSiteSchedules publishes and unpublishes the sites whose scheduled time
+// has come. It is meant to be run periodically by the scheduler
+func (h *Handler) RunSiteSchedules(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	toPublish, err := h.Queries().GetSitesDueToPublish(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, siteID := range toPublish {
+		if err := h.runSiteSchedule(ctx, siteID, true); err != nil {
+			h.Log().Error("error running scheduled publish", "site_id", siteID, "error", err)
+			continue
+		}
+		h.Log().Info("scheduled publish", "site_id", siteID)
+	}
+
+	toUnpublish, err := h.Queries().GetSitesDueToUnpublish(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, siteID := range toUnpublish {
+		if err := h.runSiteSchedule(ctx, siteID, false); err != nil {
+			h.Log().Error("error running scheduled unpublish", "site_id", siteID, "error", err)
+			continue
+		}
+		h.Log().Info("scheduled unpublish", "site_id", siteID)
+	}
+
+	return nil
+}
+
+func (h *Handler) runSiteSchedule(ctx context.Context, siteID int64, publish bool) error {
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if publish {
+		if err := qtx.PublishSite(ctx, siteID); err != nil {
+			return err
+		}
+		if err := qtx.ClearSitePublishAt(ctx, siteID); err != nil {
+			return err
+		}
+	} else {
+		if err := qtx.UnpublishSite(ctx, siteID); err != nil {
+			return err
+		}
+		if err := qtx.ClearSiteUnpublishAt(ctx, siteID); err != nil {
+			return err
+		}
+	}
+
+	site, err := qtx.GetSiteByID(ctx, siteID)
+	if err != nil {
+		return err
+	}
+
+	action := auditSiteUnpublish
+	if publish {
+		action = auditSitePublish
+	}
+	if err := audit(ctx, qtx, nil, auditEvent{
+		action:     action,
+		targetType: auditTargetSite,
+		target:     site.SiteID,
+		payload:    map[string]any{"slug": site.SiteSlug, "scheduled": true},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	return nil
+}
+
+func (h *Handler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		PublishAt   string `json:"publish_at"`
+		UnpublishAt string `json:"unpublish_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid schedule request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	publishAt, errPublish := utils.DateTimeLocalToUnix(req.PublishAt)
+	unpublishAt, errUnpublish := utils.DateTimeLocalToUnix(req.UnpublishAt)
+	if errPublish != nil || errUnpublish != nil {
+		h.Log().Debug("invalid schedule dates", "publish_at", req.PublishAt, "unpublish_at", req.UnpublishAt)
+		templates.Notice(
+			templates.ScheduleNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("editor_schedule_invalid"),
+		).Render(ctx, w)
+		return
+	}
+
+	if publishAt > 0 && unpublishAt > 0 && unpublishAt <= publishAt {
+		h.Log().Debug("unpublish time before publish time", "publish_at", publishAt, "unpublish_at", unpublishAt)
+		templates.Notice(
+			templates.ScheduleNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("editor_schedule_invalid"),
+		).Render(ctx, w)
+		return
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		h.Log().Error("error starting tx", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdateSiteSchedule(ctx, db.UpdateSiteScheduleParams{
+		SitePublishAtUnix:   publishAt,
+		SiteUnpublishAtUnix: unpublishAt,
+		SiteID:              site.SiteID,
+	}); err != nil {
+		h.Log().Error("error updating schedule", "error", err)
+		templates.Notice(
+			templates.ScheduleNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	// Same as publishing, sites scheduled for a later date are hidden until
+	// the scheduler publishes them
+	if site.SitePublished == 1 && publishAt > time.Now().Unix() {
+		if err := qtx.UnpublishSite(ctx, site.SiteID); err != nil {
+			h.Log().Error("error unpublishing site", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := audit(ctx, qtx, r, auditEvent{
+			actor:      site.SiteUser,
+			action:     auditSiteUnpublish,
+			targetType: auditTargetSite,
+			target:     site.SiteID,
+			payload:    map[string]any{"slug": site.SiteSlug, "publish_at": publishAt},
+		}); err != nil {
+			h.Log().Error("error auditing site unpublishing", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		h.Log().Error("commit failed", "error", err)
+		templates.Notice(
+			templates.ScheduleNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	h.Log().Debug("updated schedule", "site_id", site.SiteID, "publish_at", publishAt, "unpublish_at", unpublishAt)
+
+	if err := templates.Notice(
+		templates.ScheduleNoticeID,
+		templates.NoticeInfo,
+		tr("success"),
+		tr("editor_schedule_saved"),
+	).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
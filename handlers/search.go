@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"app/config"
+	"app/cursor"
+	"app/database"
+	"app/internal/db"
+	"app/templates"
+	"app/utils"
+)
+
+// searchIndexBatch is how many unindexed sites IndexSites indexes per run
+const searchIndexBatch = 100
+
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	tr := h.Translator(r)
+	ctx := r.Context()
+
+	jsonStr := r.URL.Query().Get("datastar")
+
+	var payload struct {
+		Query string `json:"search"`
+	}
+
+	if err := json.Unmarshal([]byte(jsonStr), &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	query := strings.TrimSpace(payload.Query)
+
+	// Empty searches browse every site from the most visited, the others
+	// are ranked in the text search configuration of the locale. Ranks only
+	// compare within a query, so its cursors are bound to it
+	order := "search"
+	if query != "" {
+		sum := sha256.Sum256([]byte(query))
+		order = "search:" + tr("lang") + ":" + hex.EncodeToString(sum[:8])
+	}
+
+	var after *cursor.Cursor
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		c, err := cursor.Decode(h.params.ServerSecret, token, order)
+		if err != nil {
+			h.Log().Debug("invalid search cursor", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		after = &c
+	}
+
+	var (
+		sites      []db.SitesWithMetric
+		highlights map[int64]string
+		ranks      []float64
+		err        error
+	)
+
+	if query == "" {
+		sites, err = h.browseSites(ctx, after)
+	} else {
+		sites, highlights, ranks, err = h.searchSites(ctx, tr("lang"), query, after)
+	}
+	if err != nil {
+		h.Log().Error("error searching sites", "error", err, "query", query)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.Log().Debug("sites with metric matched", "count", len(sites))
+
+	next := ""
+	if len(sites) == sitesPerPage {
+		last := sites[len(sites)-1]
+
+		c := cursor.Cursor{
+			Order:  order,
+			Page:   nextPage(after),
+			Site:   last.SiteID,
+			Visits: last.MetricVisitsTotal,
+		}
+		if query != "" {
+			c.Score = ranks[len(ranks)-1]
+		}
+
+		token, err := cursor.Encode(h.params.ServerSecret, c)
+		if err != nil {
+			h.Log().Error("error encoding search cursor", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		next = config.Endpoints[config.SearchPath] + "?" + url.Values{"cursor": {token}}.Encode()
+	}
+
+	if after != nil {
+		if err := templates.CardsPage(tr, sites, highlights, after.Page, next).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	if err := templates.PagedCardsGrid(tr, sites, highlights, next).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// browseSites returns the page of published sites after the given cursor,
+// from the most visited
+func (h *Handler) browseSites(ctx context.Context, after *cursor.Cursor) ([]db.SitesWithMetric, error) {
+	// The first page starts above any visit count
+	pos := cursor.Cursor{Visits: math.MaxInt64}
+	if after != nil {
+		pos = *after
+	}
+
+	return h.Queries().GetValidSitesWithMetricsFromMostTotalVisitsLessThan(ctx, db.GetValidSitesWithMetricsFromMostTotalVisitsLessThanParams{
+		MetricVisitsTotal:   pos.Visits,
+		MetricVisitsTotal_2: pos.Visits,
+		SiteID:              pos.Site,
+	})
+}
+
+// searchSites returns the page of published sites matching query after the
+// given cursor, best ranked first, along with their highlighted headlines by
+// site id and their ranks. Spanish locales are searched with the spanish text
+// configuration, any other with the english one
+func (h *Handler) searchSites(ctx context.Context, lang, query string, after *cursor.Cursor) ([]db.SitesWithMetric, map[int64]string, []float64, error) {
+	// The first page starts above any rank
+	rank, site := math.Inf(1), int64(0)
+	if after != nil {
+		rank, site = after.Score, after.Site
+	}
+
+	var (
+		sites      []db.SitesWithMetric
+		highlights = make(map[int64]string)
+		ranks      []float64
+	)
+
+	if lang == "es" {
+		rows, err := h.Queries().SearchSitesSpanish(ctx, db.SearchSitesSpanishParams{
+			Query: query,
+			Rank:  rank,
+			Site:  site,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, row := range rows {
+			sites = append(sites, row.SitesWithMetric)
+			highlights[row.SitesWithMetric.SiteID] = row.SearchHeadline
+			ranks = append(ranks, row.SearchRank)
+		}
+		return sites, highlights, ranks, nil
+	}
+
+	rows, err := h.Queries().SearchSitesEnglish(ctx, db.SearchSitesEnglishParams{
+		Query: query,
+		Rank:  rank,
+		Site:  site,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, row := range rows {
+		sites = append(sites, row.SitesWithMetric)
+		highlights[row.SitesWithMetric.SiteID] = row.SearchHeadline
+		ranks = append(ranks, row.SearchRank)
+	}
+	return sites, highlights, ranks, nil
+}
+
+// indexSite stores the search document of a site from its published
+// content, it should run in the transaction that changes the site
+func indexSite(ctx context.Context, queries *db.Queries, siteID int64, title, description string, htmlGz []byte) error {
+	var body string
+
+	if len(htmlGz) > 0 {
+		html, err := utils.Gunzip(htmlGz)
+		if err != nil {
+			return err
+		}
+		body = database.PlainText(string(html))
+	}
+
+	tags, err := queries.GetSiteTags(ctx, siteID)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.TagName
+	}
+
+	return queries.UpsertSiteSearch(ctx, db.UpsertSiteSearchParams{
+		SearchSite:        siteID,
+		SearchTitle:       title,
+		SearchDescription: description,
+		SearchTags:        strings.Join(names, " "),
+		SearchBody:        body,
+	})
+}
+
+// IndexSites indexes the sites without a search document, such as those
+// published before search was added. It is meant to be run periodically by
+// the scheduler
+func (h *Handler) IndexSites(ctx context.Context) error {
+	sites, err := h.Queries().GetSitesMissingSearch(ctx, searchIndexBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sites {
+		if err := indexSite(ctx, h.Queries(), s.SiteID, s.SiteTitle, s.SiteDescription, s.SiteHtmlGz); err != nil {
+			h.Log().Error("error indexing site", "error", err, "site", s.SiteSlug)
+		}
+	}
+
+	return nil
+}
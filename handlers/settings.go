@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"app/database"
@@ -61,6 +62,23 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+
+	matches := h.moderate(map[string]string{"tags": strings.Join(names, " ")})
+	if rejected(matches) {
+		h.Log().Debug("rejected tags", "term", matches[0].Term)
+		templates.Notice(
+			templates.UpdateSettingsNoticeID,
+			templates.NoticeWarn,
+			tr("warn"),
+			tr("moderation_rejected"),
+		).Render(ctx, w)
+		return
+	}
+
 	if req.HomePage == "" && len(tags) == 0 {
 		h.Log().Debug("empty request, not doing anything")
 		return
@@ -115,21 +133,53 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		showHomePage = site.SiteHomePage
 	}
 
-	json := database.TagsToJSON(tags)
-
-	if database.TagsToCommaList(json) == database.TagsToCommaList(site.SiteTagsJson) {
-		json = site.SiteTagsJson
+	qtx := h.Queries().WithTx(tx)
+
+	// Tags are only rewritten when their names change, so the colors and
+	// order stay as they are when the request only toggles the home page
+	if database.TagsToCommaList(database.TagsToJSON(tags)) != database.TagsToCommaList(site.SiteTagsJson) {
+		h.Log().Debug("updating tags", "tags", tags)
+
+		if err := setSiteTags(ctx, qtx, site.SiteID, tags); err != nil {
+			h.Log().Error("error updating tags", "error", err)
+			templates.Notice(
+				templates.UpdateSettingsNoticeID,
+				templates.NoticeError,
+				tr("error"),
+				tr("try_later"),
+			).Render(ctx, w)
+			return
+		}
+
+		if err := flagSite(ctx, qtx, site.SiteID, matches); err != nil {
+			h.Log().Error("error flagging site", "error", err)
+			templates.Notice(
+				templates.UpdateSettingsNoticeID,
+				templates.NoticeError,
+				tr("error"),
+				tr("try_later"),
+			).Render(ctx, w)
+			return
+		}
 	}
 
-	h.Log().Debug("updating tags", "tags", tags, "json", json)
-
-	if err := h.Queries().UpdateSiteSettings(ctx, db.UpdateSiteSettingsParams{
-		SiteTagsJson:     json,
+	if err := qtx.UpdateSiteSettings(ctx, db.UpdateSiteSettingsParams{
 		SiteModifiedUnix: time.Now().Unix(),
 		SiteHomePage:     showHomePage,
 		SiteID:           site.SiteID,
 	}); err != nil {
-		h.Log().Debug("error updating tags", "error", err)
+		h.Log().Debug("error updating settings", "error", err)
+		templates.Notice(
+			templates.UpdateSettingsNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+
+	if err := indexSite(ctx, qtx, site.SiteID, site.SiteTitle, site.SiteDescription, site.SiteHtmlGz); err != nil {
+		h.Log().Error("error indexing site", "error", err)
 		templates.Notice(
 			templates.UpdateSettingsNoticeID,
 			templates.NoticeError,
@@ -158,7 +208,14 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	site.SiteTagsJson = json
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	site, err = h.Queries().GetSiteWithMetrics(ctx, site.SiteSlug)
+	if err != nil {
+		h.Log().Error("error querying site", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	if err := templates.EditorTags(tr, site).Render(ctx, w); err != nil {
 		h.Log().Error("error rendering template", "error", err)
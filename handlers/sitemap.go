@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/internal/db"
+	"app/sitemap"
+	"app/utils"
+)
+
+// robotsDisallowed are the app paths crawlers have no business indexing
+var robotsDisallowed = []config.Endpoint{
+	config.EditorPath,
+	config.DashboardPath,
+	config.RegisterPath,
+	config.LoginPath,
+	config.LogoutPath,
+	config.AccountPath,
+	config.UploadPath,
+	config.SettingsPath,
+	config.BannerPath,
+	config.CheckoutPath,
+	config.SearchPath,
+	config.RevisionsPath,
+	config.SchedulePath,
+	config.DomainsPath,
+	config.PagesPath,
+	config.ExportPath,
+	config.MarkdownPath,
+	config.AnalyticsPath,
+	config.ReportPath,
+	config.ModerationPath,
+	config.AdminPath,
+}
+
+// Sitemap writes the sitemap index, listing one sitemap per sitemap.MaxURLs
+// published sites and pages
+func (h *Handler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	total, err := h.Queries().CountSitemapEntries(r.Context())
+	if err != nil {
+		h.Log().Error("error counting sitemap entries", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	base := utils.HostURL(r) + config.Endpoints[config.SitemapsPath]
+
+	var locs []string
+	for i := range sitemap.Chunks(total) {
+		locs = append(locs, base+strconv.FormatInt(i+1, 10)+".xml")
+	}
+
+	w.Header().Set("Content-Type", sitemap.ContentType)
+
+	if err := sitemap.WriteIndex(w, locs); err != nil {
+		h.Log().Error("error writing sitemap index", "error", err)
+		return
+	}
+}
+
+// SitemapChunk writes the {chunk}.xml sitemap listed by the index
+func (h *Handler) SitemapChunk(w http.ResponseWriter, r *http.Request) {
+	chunk, err := strconv.ParseInt(strings.TrimSuffix(r.PathValue("chunk"), ".xml"), 10, 32)
+	if err != nil || chunk < 1 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	entries, err := h.Queries().GetSitemapEntries(r.Context(), db.GetSitemapEntriesParams{
+		Limit:  sitemap.MaxURLs,
+		Offset: int32((chunk - 1) * sitemap.MaxURLs),
+	})
+	if err != nil {
+		h.Log().Error("error querying sitemap entries", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(entries) == 0 && chunk > 1 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	base := utils.HostURL(r) + config.Endpoints[config.RootPath]
+
+	urls := make([]sitemap.URL, 0, len(entries))
+	for _, e := range entries {
+		loc := base + e.Slug
+		if e.Page != "" {
+			loc += "/" + e.Page
+		}
+
+		urls = append(urls, sitemap.URL{
+			Loc:     loc,
+			LastMod: time.Unix(e.Modified, 0),
+		})
+	}
+
+	w.Header().Set("Content-Type", sitemap.ContentType)
+
+	if err := sitemap.WriteURLSet(w, urls); err != nil {
+		h.Log().Error("error writing sitemap", "error", err)
+		return
+	}
+}
+
+// Robots writes robots.txt, hiding app paths and any configured in
+// config.RobotsDisallow, or the whole app when config.RobotsDisallowAll is set
+func (h *Handler) Robots(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	b.WriteString("User-agent: *\n")
+
+	if config.RobotsDisallowAll {
+		b.WriteString("Disallow: /\n")
+	} else {
+		for _, endpoint := range robotsDisallowed {
+			path := config.Endpoints[endpoint]
+			if strings.HasSuffix(path, "/") {
+				b.WriteString("Disallow: " + path + "\n")
+				continue
+			}
+			// Rules are prefixes, anchor them so site slugs that start like
+			// an app path stay indexable
+			b.WriteString("Disallow: " + path + "$\n")
+			b.WriteString("Disallow: " + path + "?\n")
+		}
+		for _, path := range config.RobotsDisallow {
+			b.WriteString("Disallow: " + path + "\n")
+		}
+		b.WriteString("\nSitemap: " + utils.HostURL(r) + config.Endpoints[config.SitemapPath] + "\n")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		h.Log().Error("error writing robots", "error", err)
+		return
+	}
+}
@@ -1,20 +1,54 @@
 package handlers
 
 import (
+	"bytes"
 	"compress/gzip"
 	"database/sql"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"app/config"
+	"app/database"
+	"app/feeds"
+	"app/internal/db"
+	"app/pagecache"
 	"app/templates"
+	"app/utils"
 )
 
 func (h *Handler) Site(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("site")
+	h.renderSite(w, r, slug, "", config.Endpoints[config.RootPath]+slug)
+}
+
+func (h *Handler) SitePage(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("site")
+	h.renderSite(w, r, slug, r.PathValue("page"), config.Endpoints[config.RootPath]+slug)
+}
+
+// renderSite writes the public page of a published site, or one of its pages
+// when pageSlug is set. It is shared by the slug routes and custom domains,
+// baseURL is the path the site is served under
+func (h *Handler) renderSite(w http.ResponseWriter, r *http.Request, siteSlug, pageSlug, baseURL string) {
 	ctx := r.Context()
 
-	siteSlug := r.PathValue("site")
+	tr := h.Translator(r)
+
+	// Rendered pages depend on the host and base path (canonical and
+	// navigation links) and on the locale
+	cacheKey := utils.HostURL(r) + baseURL + "/" + pageSlug + "\x00" + tr("lang")
+	generation := h.PageCache().Generation()
+
+	// Clients with a session may own the site, they skip the cache so it is
+	// only checked for them
+	if page, ok := h.PageCache().Get(cacheKey); ok && !h.hasSessionCookie(r) {
+		h.countVisit(r, page.SiteID, baseURL)
+		h.writePage(w, r, page)
+		return
+	}
 
 	site, err := h.Queries().GetPublishedSiteWithMetricsBySlug(ctx, siteSlug)
 	if err != nil || site.SitePublished != 1 {
@@ -27,17 +61,102 @@ func (h *Handler) Site(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwner := false
-	if s, err := h.verifyClient(
-		w,
-		r,
-		false,
-	); s.SessionUser == site.SiteUser && err == nil {
-		h.Log().Debug("is owner")
-		isOwner = true
+	content := templates.Site(site)
+
+	userFeed := config.Endpoints[config.FeedsPath] + "sites/" + site.SiteSlug + "/"
+
+	head := templates.SiteHead{
+		Title:       site.SiteTitle,
+		Description: site.SiteDescription,
+		Feeds: []templates.FeedLink{
+			{Title: tr("feeds_user_title") + " (RSS)", URL: userFeed + feeds.FormatRSS, Type: "application/rss+xml"},
+			{Title: tr("feeds_user_title") + " (Atom)", URL: userFeed + feeds.FormatAtom, Type: "application/atom+xml"},
+		},
+		Type: "article",
+	}
+
+	canonical := utils.HostURL(r) + baseURL
+	if pageSlug != "" {
+		canonical += "/" + pageSlug
+	} else if baseURL == "" {
+		canonical += "/"
+	}
+	head.URL = canonical
+
+	article := templates.Article{
+		Context:       "https://schema.org",
+		Type:          "Article",
+		Headline:      site.SiteTitle,
+		Description:   site.SiteDescription,
+		URL:           canonical,
+		DatePublished: time.Unix(site.SiteCreatedUnix, 0).UTC().Format(time.RFC3339),
+		DateModified:  time.Unix(site.SiteModifiedUnix, 0).UTC().Format(time.RFC3339),
+	}
+
+	for _, tag := range database.JSONToTags(site.SiteTagsJson) {
+		article.Keywords = append(article.Keywords, tag.Name)
+	}
+
+	lastModified := site.SiteModifiedUnix
+
+	if pageSlug != "" {
+		page, err := h.Queries().GetPage(ctx, db.GetPageParams{
+			PageSite: site.SiteID,
+			PageSlug: pageSlug,
+		})
+		if err != nil || page.PagePublished != 1 {
+			h.Log().Debug("cannot find published page", "siteSlug", siteSlug, "pageSlug", pageSlug)
+			if err := templates.NotFound(h.Translator(r)).Render(ctx, w); err != nil {
+				h.Log().Error("error rendering template", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		content = templates.Page(page)
+		head.Title = page.PageTitle + " | " + site.SiteTitle
+
+		article.Headline = page.PageTitle
+		article.DatePublished = time.Unix(page.PageCreatedUnix, 0).UTC().Format(time.RFC3339)
+		article.DateModified = time.Unix(page.PageModifiedUnix, 0).UTC().Format(time.RFC3339)
+
+		lastModified = max(lastModified, page.PageModifiedUnix)
+	}
+
+	pages, err := h.Queries().GetPublishedPagesBySite(ctx, site.SiteID)
+	if err != nil {
+		h.Log().Error("error loading pages", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var nav []templates.NavLink
+	if len(pages) > 0 {
+		home := baseURL
+		if home == "" {
+			home = "/"
+		}
+
+		nav = append(nav, templates.NavLink{
+			Title:  site.SiteTitle,
+			URL:    home,
+			Active: pageSlug == "",
+		})
+
+		for _, p := range pages {
+			nav = append(nav, templates.NavLink{
+				Title:  p.PageTitle,
+				URL:    baseURL + "/" + p.PageSlug,
+				Active: p.PageSlug == pageSlug,
+			})
+		}
 	}
 
+	isOwner := h.isSessionUser(r, site.SiteUser)
+
 	bannerURL := ""
+	bannerMD5 := ""
 
 	banner, err := h.Queries().GetBanner(ctx, site.SiteID)
 	if err != nil {
@@ -56,30 +175,88 @@ func (h *Handler) Site(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		bannerURL = config.S3PublicURL + "/" + object.ObjectKey
+		bannerMD5 = object.ObjectMd5
+		lastModified = max(lastModified, object.ObjectModifiedUnix)
 	}
 
-	tr := h.Translator(r)
+	h.countVisit(r, site.SiteID, baseURL)
 
-	header := templates.SiteHeader(tr, site, bannerURL, isOwner)
-	content := templates.Site(site)
+	head.Image = bannerURL
+	article.Image = bannerURL
+	head.JSONLD = article
 
-	head := templates.SiteHead{
-		Title:       site.SiteTitle,
-		Description: site.SiteDescription,
+	header := templates.SiteHeader(tr, site, bannerURL, isOwner, nav)
+	content = templates.SiteBody(tr, site.SiteSlug, content)
+
+	// Owners get an extra navigation bar, their responses are never cached
+	if isOwner {
+		w.Header().Set("Cache-Control", "private, no-store")
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			w.Header().Add("Content-Type", "text/html")
+			w.Header().Add("Content-Encoding", "gzip")
+			templates.Base(tr, header, content, &head, false).Render(ctx, gz)
+		} else {
+			templates.Base(tr, header, content, &head, false).Render(ctx, w)
+		}
+		return
 	}
 
-	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		w.Header().Add("Content-Type", "text/html")
-		w.Header().Add("Content-Encoding", "gzip")
-		templates.Base(tr, header, content, &head, false).Render(ctx, gz)
-	} else {
-		templates.Base(tr, header, content, &head, false).Render(ctx, w)
+	validators := []string{
+		strconv.FormatInt(site.SiteID, 10),
+		strconv.FormatInt(lastModified, 10),
+		bannerMD5,
+		pageSlug,
+		tr("lang"),
+	}
+	for _, link := range nav {
+		validators = append(validators, link.Title, link.URL)
 	}
 
-	if err := h.Queries().NewVisit(ctx, site.SiteID); err != nil {
-		h.Log().Error("error incrementing visit", "error", err)
+	var html bytes.Buffer
+	if err := templates.Base(tr, header, content, &head, false).Render(ctx, &html); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page, err := pagecache.NewPage(
+		site.SiteSlug,
+		site.SiteID,
+		site.SiteUser,
+		weakETag(validators...),
+		time.Unix(lastModified, 0),
+		html.Bytes(),
+	)
+	if err != nil {
+		h.Log().Error("error compressing page", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	h.PageCache().Put(cacheKey, page, generation)
+
+	h.writePage(w, r, page)
+}
+
+// hasSessionCookie reports whether the client sent a session cookie, valid or
+// not
+func (h *Handler) hasSessionCookie(r *http.Request) bool {
+	_, err := r.Cookie(h.params.CookieName)
+	return err == nil
+}
+
+// isSessionUser reports whether the client has a valid session of user. Unlike
+// verifyClient it never refreshes the session or the CSRF cookies, public
+// responses may be cached and must not carry a Set-Cookie
+func (h *Handler) isSessionUser(r *http.Request, user int64) bool {
+	s, _, err := h.Sessions.JWTValidate(r)
+	if err != nil || s.SessionUser != user {
+		return false
+	}
+
+	exists, err := h.Queries().SessionExists(r.Context(), s.SessionID)
+	return err == nil && exists
 }
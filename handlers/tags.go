@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"app/config"
+	"app/cursor"
+	"app/database"
+	"app/internal/db"
+	"app/templates"
+)
+
+const (
+	// tagCloudSize is how many of the most used tags the home page shows
+	tagCloudSize = 30
+)
+
+func (h *Handler) TagPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	name := strings.ToLower(r.PathValue("tag"))
+
+	tag, err := h.Queries().GetTag(ctx, name)
+	if err != nil {
+		h.Log().Debug("cannot find tag", "tag", name, "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		if err := templates.NotFound(tr).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+		}
+		return
+	}
+
+	order := "tag:" + tag.TagName
+
+	var after *cursor.Cursor
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		c, err := cursor.Decode(h.params.ServerSecret, token, order)
+		if err != nil {
+			h.Log().Debug("invalid tag cursor", "error", err, "tag", tag.TagName)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		after = &c
+	}
+
+	// The first page starts above any visit count
+	pos := cursor.Cursor{Visits: math.MaxInt64}
+	if after != nil {
+		pos = *after
+	}
+
+	sites, err := h.Queries().GetHomePageSitesWithMetricsByTagLessThan(ctx, db.GetHomePageSitesWithMetricsByTagLessThanParams{
+		Tag:    tag.TagID,
+		Visits: pos.Visits,
+		Site:   pos.Site,
+	})
+	if err != nil {
+		h.Log().Error("error querying sites by tag", "error", err, "tag", tag.TagName)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	next := ""
+	if len(sites) == sitesPerPage {
+		last := sites[len(sites)-1]
+		token, err := cursor.Encode(h.params.ServerSecret, cursor.Cursor{
+			Order:  order,
+			Page:   nextPage(after),
+			Site:   last.SiteID,
+			Visits: last.MetricVisitsTotal,
+		})
+		if err != nil {
+			h.Log().Error("error encoding tag cursor", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		next = config.Endpoints[config.TagsPath] + url.PathEscape(tag.TagName) + "?" + url.Values{"cursor": {token}}.Encode()
+	}
+
+	if after != nil {
+		if err := templates.CardsPage(tr, sites, nil, after.Page, next).Render(ctx, w); err != nil {
+			h.Log().Error("error rendering template", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	header := templates.TagHeader(tr, tag.TagName)
+	content := templates.PagedCardsGrid(tr, sites, nil, next)
+
+	head := templates.SiteHead{
+		Title:       config.AppTitle + " | #" + tag.TagName,
+		Description: tr("tags_page_description") + " " + tag.TagName,
+	}
+
+	if err := templates.Base(tr, header, content, &head, true).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) UpdateTagColor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tr := h.Translator(r)
+
+	site, status, ok := h.ownedSite(r)
+	if !ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var req struct {
+		Color string `json:"tag_color"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log().Debug("invalid tag color request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !database.IsTagColor(req.Color) {
+		h.Log().Debug("invalid tag color", "color", req.Color)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Queries().UpdateSiteTagColor(ctx, db.UpdateSiteTagColorParams{
+		SiteTagColor: req.Color,
+		SiteTagSite:  site.SiteID,
+		TagName:      r.PathValue("tag"),
+	})
+	if err != nil {
+		h.Log().Error("error updating tag color", "error", err)
+		templates.Notice(
+			templates.UpdateSettingsNoticeID,
+			templates.NoticeError,
+			tr("error"),
+			tr("try_later"),
+		).Render(ctx, w)
+		return
+	}
+	if updated == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	h.PageCache().Invalidate(site.SiteSlug)
+
+	withMetrics, err := h.Queries().GetSiteWithMetrics(ctx, site.SiteSlug)
+	if err != nil {
+		h.Log().Error("error querying site", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.EditorTags(tr, withMetrics).Render(ctx, w); err != nil {
+		h.Log().Error("error rendering template", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// setSiteTags replaces the tags of a site keeping the order of tags, tags
+// the site already had keep their color and new ones get theirs or the
+// default one of the tag
+func setSiteTags(ctx context.Context, queries *db.Queries, siteID int64, tags []database.Tag) error {
+	current, err := queries.GetSiteTags(ctx, siteID)
+	if err != nil {
+		return err
+	}
+
+	colors := make(map[string]string, len(current))
+	for _, t := range current {
+		colors[t.TagName] = t.SiteTagColor
+	}
+
+	if err := queries.DeleteSiteTags(ctx, siteID); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+
+	for i, t := range tags {
+		tagID, err := queries.UpsertTag(ctx, db.UpsertTagParams{
+			TagName:        t.Name,
+			TagCreatedUnix: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		color, ok := colors[t.Name]
+		if !ok {
+			color = t.Color
+		}
+		if !database.IsTagColor(color) {
+			color = database.DefaultTagColor(t.Name)
+		}
+
+		if err := queries.InsertSiteTag(ctx, db.InsertSiteTagParams{
+			SiteTagSite:     siteID,
+			SiteTagTag:      tagID,
+			SiteTagColor:    color,
+			SiteTagPosition: int64(i),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"app/internal/db"
+)
+
+const (
+	// trendingHalfLife is how many days it takes a visitor to weigh half as
+	// much in the trending score
+	trendingHalfLife = 3.0
+
+	// trendingWindow is how many days of visitors the trending score adds up,
+	// older ones weigh too little to matter
+	trendingWindow = 14
+)
+
+// UpdateTrending recomputes the trending score of every site from its recent
+// daily unique visitors, each day weighted down exponentially by its age, and
+// ranks sites by it. It is meant to be run periodically by the scheduler
+func (h *Handler) UpdateTrending(ctx context.Context) error {
+	now := time.Now()
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.UpdateTrendingScores(ctx, db.UpdateTrendingScoresParams{
+		NowUnix:      now.Unix(),
+		HalfLifeDays: trendingHalfLife,
+		SinceUnix:    startOfDay(now).AddDate(0, 0, 1-trendingWindow).Unix(),
+	}); err != nil {
+		return err
+	}
+
+	// Scores decay between runs, pages of trending sites follow the ranks so
+	// a cursor keeps its place until the next run
+	if err := qtx.RankTrendingSites(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mileusna/useragent"
+
+	"app/internal/db"
+	"app/utils"
+	"app/visits"
+)
+
+// FlushVisits writes the buffered visit counts, it is run by the scheduler
+// and once more on shutdown
+func (h *Handler) FlushVisits(ctx context.Context) error {
+	err := h.Visits().Flush(ctx)
+
+	if visitors, sources := h.Visits().TakeDropped(); visitors > 0 || sources > 0 {
+		h.Log().Error("dropped buffered visits", "visitors", visitors, "sources", sources)
+	}
+
+	return err
+}
+
+// PruneVisitors deletes the visitor hashes of past days, they are only needed
+// to deduplicate visitors within the current day
+func (h *Handler) PruneVisitors(ctx context.Context) error {
+	return h.Queries().DeleteVisitorsBefore(ctx, startOfDay(time.Now()).Unix())
+}
+
+// countVisit buffers a visit to site, served under baseURL, along with where
+// it came from. Requests from bots and crawlers are not counted
+func (h *Handler) countVisit(r *http.Request, site int64, baseURL string) {
+	ua := r.UserAgent()
+	if ua == "" || useragent.Parse(ua).Bot {
+		return
+	}
+
+	now := time.Now()
+	query := r.URL.Query()
+
+	h.Visits().Add(
+		site,
+		visits.NewVisitor(h.params.ServerSecret, site, now, utils.ClientIP(r), ua),
+		visits.Source{
+			Site:        site,
+			DayUnix:     startOfDay(now).Unix(),
+			Referrer:    truncate(referrerHost(r, baseURL), 253),
+			UTMSource:   truncate(query.Get("utm_source"), 63),
+			UTMMedium:   truncate(query.Get("utm_medium"), 63),
+			UTMCampaign: truncate(query.Get("utm_campaign"), 63),
+		},
+	)
+}
+
+// referrerHost returns the host of the Referer header, or an empty string
+// when there is none or it is a page of the site itself, served under baseURL
+// on this host
+func referrerHost(r *http.Request, baseURL string) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || ref.Host == "" {
+		return ""
+	}
+
+	host := strings.ToLower(ref.Hostname())
+
+	if host == strings.ToLower(strings.Split(utils.Host(r), ":")[0]) {
+		if baseURL == "" || ref.Path == baseURL || strings.HasPrefix(ref.Path, baseURL+"/") {
+			return ""
+		}
+	}
+
+	return host
+}
+
+// truncate cuts s to at most n bytes without splitting a rune
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// addVisits adds counts to the site totals and to today's daily bucket, the
+// visitors not seen before today to the daily uniques and the sources to the
+// daily referrers, in a single transaction. Visits are bucketed by flush time,
+// which is at most one flush interval after they happened
+func (h *Handler) addVisits(ctx context.Context, batch visits.Batch) error {
+	sites := make([]int64, 0, len(batch.Counts))
+	totals := make([]int64, 0, len(batch.Counts))
+
+	for site, n := range batch.Counts {
+		sites = append(sites, site)
+		totals = append(totals, n)
+	}
+
+	unique := db.AddDailyVisitorsParams{
+		Sites:  make([]int64, 0, len(batch.Visitors)),
+		Days:   make([]int64, 0, len(batch.Visitors)),
+		Hashes: make([]string, 0, len(batch.Visitors)),
+	}
+
+	for _, v := range batch.Visitors {
+		unique.Sites = append(unique.Sites, v.Site)
+		unique.Days = append(unique.Days, v.DayUnix)
+		unique.Hashes = append(unique.Hashes, v.ID)
+	}
+
+	var referrers db.AddDailyReferrersParams
+
+	for source, n := range batch.Sources {
+		referrers.Sites = append(referrers.Sites, source.Site)
+		referrers.Days = append(referrers.Days, source.DayUnix)
+		referrers.Hosts = append(referrers.Hosts, source.Referrer)
+		referrers.UtmSources = append(referrers.UtmSources, source.UTMSource)
+		referrers.UtmMediums = append(referrers.UtmMediums, source.UTMMedium)
+		referrers.UtmCampaigns = append(referrers.UtmCampaigns, source.UTMCampaign)
+		referrers.Visits = append(referrers.Visits, n)
+	}
+
+	tx, err := h.DB().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.Queries().WithTx(tx)
+
+	if err := qtx.AddVisits(ctx, db.AddVisitsParams{
+		Sites:  sites,
+		Visits: totals,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.AddDailyVisits(ctx, db.AddDailyVisitsParams{
+		DayUnix: startOfDay(time.Now()).Unix(),
+		Sites:   sites,
+		Visits:  totals,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.AddDailyVisitors(ctx, unique); err != nil {
+		return err
+	}
+
+	if len(batch.Sources) > 0 {
+		if err := qtx.AddDailyReferrers(ctx, referrers); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// startOfDay truncates t to midnight UTC, daily buckets are keyed by it
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -28,9 +28,11 @@ var EN = map[string]string{
 
 	// home
 	"create_site":      "Create website",
+	"trending":         "Trending",
 	"most_visited":     "Most visited",
 	"recently_created": "Recently created",
 	"describe_site":    "Describe what you need",
+	"load_more":        "Load more",
 
 	// navbar
 	"back_to": "Back to",
@@ -89,11 +91,190 @@ var EN = map[string]string{
 	"editor_unpublish_site":          "Unpublish",
 	"editor_site_not_published":      "Site is not published",
 	"editor_currently_published":     "Currently published",
+	"editor_unpublished_changes":     "Unpublished changes",
+	"editor_save_draft":              "Save draft",
+	"editor_schedule":                "Schedule",
+	"editor_schedule_publish_at":     "Publish on",
+	"editor_schedule_unpublish_at":   "Unpublish on",
+	"editor_schedule_hint":           "Times are in Costa Rica time. Leave empty to disable",
+	"editor_schedule_save":           "Save schedule",
+	"editor_schedule_invalid":        "The unpublish time must come after the publish time",
+	"editor_schedule_saved":          "Schedule saved",
 	"editor_delete_site":             "Delete site",
 	"editor_delete_site_button":      "Delete",
 	"editor_delete_site_prompt":      "Once deleted, all the site data will be permanently lost",
 	"editor_delete_site_permanent":   "Delete permanently",
 
+	// revisions
+	"revisions":                   "Version history",
+	"revisions_kept":              "The last %d published versions are kept",
+	"revisions_empty":             "Publish this site to start its version history",
+	"revisions_date":              "Date",
+	"revisions_title":             "Title",
+	"revisions_restore_published": "Publish",
+	"revisions_restore_draft":     "Edit",
+	"revisions_from":              "Compare",
+	"revisions_to":                "With",
+	"revisions_compare":           "Show changes",
+	"revisions_select_two":        "Select two versions to compare",
+	"revisions_restored":          "Version published",
+	"revisions_too_large":         "These versions differ too much to compare",
+
+	// domains
+	"domains":              "Custom domains",
+	"domains_add":          "Add a domain you own",
+	"domains_instructions": "Add this DNS record to your domain, then verify it within 3 days",
+	"domains_point":        "Verified. Point the domain DNS to this server to serve the site",
+	"domains_verify":       "Verify",
+	"domains_remove":       "Remove",
+	"domains_upgrade":      "Upgrade plan to use a custom domain",
+	"domains_invalid":      "Invalid domain",
+	"domains_limit":        "You reached the maximum number of domains for this site",
+	"domains_unavailable":  "This domain is not available",
+	"domains_not_verified": "Could not find the verification record yet, DNS changes can take a while to propagate",
+
+	// pages
+	"pages":                        "Pages",
+	"pages_new_title":              "New page title",
+	"pages_new_title_placeholder":  "About us",
+	"pages_new_slug":               "Page address",
+	"pages_create":                 "Create page",
+	"pages_delete_prompt":          "Delete this page permanently?",
+	"pages_maximum_reached":        "This site reached the maximum number of pages",
+	"pages_upgrade_to_create_more": "Upgrade plan to create more pages",
+	"pages_published":              "Page published",
+
+	// export
+	"export":             "Export",
+	"export_description": "Download a ZIP with the published page and all its images, ready to host anywhere.",
+	"export_download":    "Download ZIP",
+
+	// markdown
+	"markdown":               "Markdown",
+	"markdown_import_hint":   "Replace the editor content with a Markdown file, or download the published content as Markdown.",
+	"markdown_import":        "Import .md",
+	"markdown_export":        "Export .md",
+	"markdown_import_prompt": "The current editor content will be replaced, continue?",
+	"markdown_invalid_file":  "Choose a .md file",
+
+	// feeds
+	"feeds_tag_description":  "Sites tagged",
+	"feeds_user_title":       "More sites by this author",
+	"feeds_user_description": "Recently published and updated sites by this author",
+
+	// analytics
+	"analytics":               "Visits",
+	"analytics_site":          "Site",
+	"analytics_all_sites":     "All sites",
+	"analytics_days":          "Last %d days",
+	"analytics_uniques":       "unique visitors",
+	"analytics_referrers_csv": "Referrers (CSV)",
+
+	// tags
+	"tags_page_description": "Sites tagged",
+	"tag_color":             "Color of tag",
+	"color_blue":            "Blue",
+	"color_purple":          "Purple",
+	"color_cyan":            "Cyan",
+	"color_green":           "Green",
+	"color_yellow":          "Yellow",
+	"color_orange":          "Orange",
+	"color_red":             "Red",
+
+	// moderation
+	"moderation_rejected": "This content is not allowed, please change it and try again",
+
+	// reports
+	"report_site":                "Report this site",
+	"report_description":         "Tell us if this site is malicious, infringes your rights or breaks our terms",
+	"report_reason":              "Reason",
+	"report_reason_pick":         "Pick a reason",
+	"report_reason_malware":      "Malware",
+	"report_reason_phishing":     "Phishing or scam",
+	"report_reason_copyright":    "Copyright infringement",
+	"report_reason_abuse":        "Harassment or abuse",
+	"report_reason_spam":         "Spam",
+	"report_reason_other":        "Other",
+	"report_details":             "Details",
+	"report_details_placeholder": "What is wrong with this site?",
+	"report_email":               "Your email (optional)",
+	"report_email_placeholder":   "So we can follow up with you",
+	"report_send":                "Send report",
+	"report_confirm_email":       "We sent a code to your email, enter it to attach your email to the report",
+	"report_sent":                "Thank you, we will review your report",
+	"report_invalid":             "Pick a reason and describe the problem in up to 1000 characters",
+	"report_rate_limited":        "You have sent too many reports, try again later",
+	"moderation":                 "Moderation",
+	"moderation_reports":         "Reports",
+	"moderation_flags":           "Flagged content",
+	"moderation_empty":           "Nothing to review",
+	"moderation_dismiss":         "Dismiss",
+	"takedown":                   "Take down",
+	"takedown_reason":            "Reason shown to the owner",
+	"takedown_reason_required":   "A reason of up to 1000 characters is required",
+	"takedown_confirm":           "Unpublish site",
+	"takedown_notice":            "A moderator unpublished",
+	"takedown_cannot_publish":    "This site was taken down by a moderator and cannot be published",
+	"takedown_email_subject":     "Your site was unpublished",
+	"takedown_email_body":        "A moderator unpublished your site",
+
+	// admin
+	"admin":                "Admin",
+	"admin_users":          "Users",
+	"admin_search_users":   "Search users by email",
+	"admin_no_users":       "No users found",
+	"admin_payments":       "Payments",
+	"admin_no_payments":    "No payments",
+	"admin_date":           "Date",
+	"admin_amount":         "Amount",
+	"admin_reference":      "Reference",
+	"admin_payment_failed": "failed",
+	"admin_user_since":     "User since",
+	"admin_role":           "Role",
+	"admin_invalid_role":   "That role cannot be granted to this user",
+	"admin_role_updated":   "Role updated",
+	"admin_plan":           "Plan",
+	"admin_plan_active":    "Active",
+	"admin_plan_due":       "Due on",
+	"admin_plan_updated":   "Plan updated",
+	"admin_invalid_date":   "Invalid date",
+	"admin_view_as":        "View as",
+	"admin_impersonating":  "You are viewing the dashboard of this user, changes are not made on their behalf",
+	"admin_sites":          "Sites",
+	"admin_no_sites":       "No sites",
+	"admin_published":      "Published",
+	"admin_unpublished":    "Unpublished",
+	"admin_restore":        "Restore",
+	"role_user":            "User",
+	"role_moderator":       "Moderator",
+	"role_admin":           "Admin",
+	"save":                 "Save",
+
+	// audit
+	"account_history":           "Account history",
+	"account_history_empty":     "No activity yet",
+	"account_history_action":    "Action",
+	"account_history_date":      "Date",
+	"audit_by_staff":            "by staff",
+	"audit_account_delete":      "Account deleted",
+	"audit_email_change":        "Email changed",
+	"audit_session_revoke":      "Device logged out",
+	"audit_session_impersonate": "Session viewed by staff",
+	"audit_site_delete":         "Site deleted",
+	"audit_site_publish":        "Site published",
+	"audit_site_unpublish":      "Site unpublished",
+	"audit_page_publish":        "Page published",
+	"audit_site_takedown":       "Site taken down",
+	"audit_site_restore":        "Site restored",
+	"audit_plan_change":         "Plan changed",
+	"audit_role_change":         "Role changed",
+	"admin_audit":               "Audit log",
+	"admin_audit_user":          "User ID",
+	"admin_audit_any_action":    "Any action",
+	"admin_audit_filter":        "Filter",
+	"admin_audit_empty":         "No events",
+	"admin_audit_older":         "Older events",
+
 	// account
 	"my_account":      "My account",
 	"device":          "Device",
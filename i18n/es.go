@@ -28,9 +28,11 @@ var ES = map[string]string{
 
 	// home
 	"create_site":      "Crear un sitio",
+	"trending":         "Tendencias",
 	"most_visited":     "Más visitados",
 	"recently_created": "Creados recientemente",
 	"describe_site":    "Describe lo que necesitas",
+	"load_more":        "Cargar más",
 
 	// navbar
 	"back_to": "Volver a",
@@ -89,11 +91,190 @@ var ES = map[string]string{
 	"editor_unpublish_site":          "No publicar",
 	"editor_site_not_published":      "El sitio web no está publicado",
 	"editor_currently_published":     "El sitio web está publicado",
+	"editor_unpublished_changes":     "Cambios sin publicar",
+	"editor_save_draft":              "Guardar borrador",
+	"editor_schedule":                "Programación",
+	"editor_schedule_publish_at":     "Publicar el",
+	"editor_schedule_unpublish_at":   "Despublicar el",
+	"editor_schedule_hint":           "Las horas están en hora de Costa Rica. Deje vacío para desactivar",
+	"editor_schedule_save":           "Guardar programación",
+	"editor_schedule_invalid":        "La hora de despublicación debe ser posterior a la de publicación",
+	"editor_schedule_saved":          "Programación guardada",
 	"editor_delete_site":             "Eliminar sitio",
 	"editor_delete_site_button":      "Eliminar",
 	"editor_delete_site_prompt":      "Una vez eliminado, se perderán permanentemente todos los datos del sitio",
 	"editor_delete_site_permanent":   "Eliminar permanentemente",
 
+	// revisions
+	"revisions":                   "Historial de versiones",
+	"revisions_kept":              "Se conservan las últimas %d versiones publicadas",
+	"revisions_empty":             "Publique este sitio para iniciar su historial de versiones",
+	"revisions_date":              "Fecha",
+	"revisions_title":             "Título",
+	"revisions_restore_published": "Publicar",
+	"revisions_restore_draft":     "Editar",
+	"revisions_from":              "Comparar",
+	"revisions_to":                "Con",
+	"revisions_compare":           "Ver cambios",
+	"revisions_select_two":        "Seleccione dos versiones para comparar",
+	"revisions_restored":          "Versión publicada",
+	"revisions_too_large":         "Estas versiones son demasiado distintas para compararlas",
+
+	// domains
+	"domains":              "Dominios propios",
+	"domains_add":          "Agregue un dominio de su propiedad",
+	"domains_instructions": "Agregue este registro DNS a su dominio y luego verifíquelo en un plazo de 3 días",
+	"domains_point":        "Verificado. Apunte el DNS del dominio a este servidor para mostrar el sitio",
+	"domains_verify":       "Verificar",
+	"domains_remove":       "Quitar",
+	"domains_upgrade":      "Mejore su plan para usar un dominio propio",
+	"domains_invalid":      "Dominio inválido",
+	"domains_limit":        "Alcanzó el número máximo de dominios para este sitio",
+	"domains_unavailable":  "Este dominio no está disponible",
+	"domains_not_verified": "Aún no se encuentra el registro de verificación, los cambios de DNS pueden tardar en propagarse",
+
+	// pages
+	"pages":                        "Páginas",
+	"pages_new_title":              "Título de la nueva página",
+	"pages_new_title_placeholder":  "Sobre nosotros",
+	"pages_new_slug":               "Dirección de la página",
+	"pages_create":                 "Crear página",
+	"pages_delete_prompt":          "¿Eliminar esta página permanentemente?",
+	"pages_maximum_reached":        "Este sitio alcanzó el número máximo de páginas",
+	"pages_upgrade_to_create_more": "Mejore su plan para crear más páginas",
+	"pages_published":              "Página publicada",
+
+	// export
+	"export":             "Exportar",
+	"export_description": "Descargue un ZIP con la página publicada y todas sus imágenes, listo para alojar en cualquier lugar.",
+	"export_download":    "Descargar ZIP",
+
+	// markdown
+	"markdown":               "Markdown",
+	"markdown_import_hint":   "Reemplace el contenido del editor con un archivo Markdown, o descargue el contenido publicado como Markdown.",
+	"markdown_import":        "Importar .md",
+	"markdown_export":        "Exportar .md",
+	"markdown_import_prompt": "El contenido actual del editor será reemplazado, ¿desea continuar?",
+	"markdown_invalid_file":  "Elija un archivo .md",
+
+	// feeds
+	"feeds_tag_description":  "Sitios con la etiqueta",
+	"feeds_user_title":       "Más sitios de este autor",
+	"feeds_user_description": "Sitios publicados y actualizados recientemente por este autor",
+
+	// analytics
+	"analytics":               "Visitas",
+	"analytics_site":          "Sitio",
+	"analytics_all_sites":     "Todos los sitios",
+	"analytics_days":          "Últimos %d días",
+	"analytics_uniques":       "visitantes únicos",
+	"analytics_referrers_csv": "Referencias (CSV)",
+
+	// tags
+	"tags_page_description": "Sitios etiquetados con",
+	"tag_color":             "Color de la etiqueta",
+	"color_blue":            "Azul",
+	"color_purple":          "Morado",
+	"color_cyan":            "Cian",
+	"color_green":           "Verde",
+	"color_yellow":          "Amarillo",
+	"color_orange":          "Naranja",
+	"color_red":             "Rojo",
+
+	// moderation
+	"moderation_rejected": "Este contenido no está permitido, cámbialo e inténtalo de nuevo",
+
+	// reports
+	"report_site":                "Reportar este sitio",
+	"report_description":         "Avísanos si este sitio es malicioso, infringe tus derechos o incumple nuestros términos",
+	"report_reason":              "Motivo",
+	"report_reason_pick":         "Escoge un motivo",
+	"report_reason_malware":      "Malware",
+	"report_reason_phishing":     "Phishing o estafa",
+	"report_reason_copyright":    "Infracción de derechos de autor",
+	"report_reason_abuse":        "Acoso o abuso",
+	"report_reason_spam":         "Spam",
+	"report_reason_other":        "Otro",
+	"report_details":             "Detalles",
+	"report_details_placeholder": "¿Qué problema tiene este sitio?",
+	"report_email":               "Tu correo (opcional)",
+	"report_email_placeholder":   "Para poder contactarte",
+	"report_send":                "Enviar reporte",
+	"report_confirm_email":       "Enviamos un código a tu correo, ingrésalo para agregar tu correo al reporte",
+	"report_sent":                "Gracias, revisaremos tu reporte",
+	"report_invalid":             "Escoge un motivo y describe el problema en hasta 1000 caracteres",
+	"report_rate_limited":        "Has enviado demasiados reportes, inténtalo más tarde",
+	"moderation":                 "Moderación",
+	"moderation_reports":         "Reportes",
+	"moderation_flags":           "Contenido marcado",
+	"moderation_empty":           "Nada que revisar",
+	"moderation_dismiss":         "Descartar",
+	"takedown":                   "Dar de baja",
+	"takedown_reason":            "Motivo que verá el dueño",
+	"takedown_reason_required":   "Se requiere un motivo de hasta 1000 caracteres",
+	"takedown_confirm":           "Despublicar sitio",
+	"takedown_notice":            "Un moderador despublicó",
+	"takedown_cannot_publish":    "Este sitio fue dado de baja por un moderador y no se puede publicar",
+	"takedown_email_subject":     "Tu sitio fue despublicado",
+	"takedown_email_body":        "Un moderador despublicó tu sitio",
+
+	// admin
+	"admin":                "Administración",
+	"admin_users":          "Usuarios",
+	"admin_search_users":   "Buscar usuarios por correo",
+	"admin_no_users":       "No se encontraron usuarios",
+	"admin_payments":       "Pagos",
+	"admin_no_payments":    "Sin pagos",
+	"admin_date":           "Fecha",
+	"admin_amount":         "Monto",
+	"admin_reference":      "Referencia",
+	"admin_payment_failed": "fallido",
+	"admin_user_since":     "Usuario desde",
+	"admin_role":           "Rol",
+	"admin_invalid_role":   "Ese rol no se le puede asignar a este usuario",
+	"admin_role_updated":   "Rol actualizado",
+	"admin_plan":           "Plan",
+	"admin_plan_active":    "Activo",
+	"admin_plan_due":       "Vence el",
+	"admin_plan_updated":   "Plan actualizado",
+	"admin_invalid_date":   "Fecha inválida",
+	"admin_view_as":        "Ver como",
+	"admin_impersonating":  "Estás viendo el panel de este usuario, los cambios no se hacen a su nombre",
+	"admin_sites":          "Sitios",
+	"admin_no_sites":       "Sin sitios",
+	"admin_published":      "Publicado",
+	"admin_unpublished":    "No publicado",
+	"admin_restore":        "Restaurar",
+	"role_user":            "Usuario",
+	"role_moderator":       "Moderador",
+	"role_admin":           "Administrador",
+	"save":                 "Guardar",
+
+	// audit
+	"account_history":           "Historial de la cuenta",
+	"account_history_empty":     "Sin actividad todavía",
+	"account_history_action":    "Acción",
+	"account_history_date":      "Fecha",
+	"audit_by_staff":            "por el equipo",
+	"audit_account_delete":      "Cuenta eliminada",
+	"audit_email_change":        "Correo cambiado",
+	"audit_session_revoke":      "Dispositivo desconectado",
+	"audit_session_impersonate": "Sesión vista por el equipo",
+	"audit_site_delete":         "Sitio eliminado",
+	"audit_site_publish":        "Sitio publicado",
+	"audit_site_unpublish":      "Sitio despublicado",
+	"audit_page_publish":        "Página publicada",
+	"audit_site_takedown":       "Sitio dado de baja",
+	"audit_site_restore":        "Sitio restaurado",
+	"audit_plan_change":         "Plan cambiado",
+	"audit_role_change":         "Rol cambiado",
+	"admin_audit":               "Registro de auditoría",
+	"admin_audit_user":          "ID de usuario",
+	"admin_audit_any_action":    "Cualquier acción",
+	"admin_audit_filter":        "Filtrar",
+	"admin_audit_empty":         "Sin eventos",
+	"admin_audit_older":         "Eventos anteriores",
+
 	// account
 	"my_account":      "Mi cuenta",
 	"device":          "Dispositivo",
@@ -7,13 +7,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"app/config"
 	"app/handlers"
 	"app/i18n"
 	"app/internal/db"
 	"app/middleware"
+	"app/moderation"
+	"app/pagecache"
 	"app/router"
+	"app/scheduler"
 
 	s3config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -24,7 +28,8 @@ var assetsFS embed.FS
 
 func main() {
 	config.Init()
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	logger, err := config.InitLogger()
 	if err != nil {
@@ -32,6 +37,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	if config.Production && config.ServerSecretGenerated {
+		logger.Error("CONEX_SECRET is not set, using a random secret: sessions end and every visitor of the day is counted again as unique on each restart")
+	}
+
 	pool, err := config.InitDB(ctx)
 	if err != nil {
 		print("failed database initialization: %v\n", err)
@@ -40,6 +49,15 @@ func main() {
 
 	queries := db.New(pool)
 
+	if len(config.Admins) > 0 {
+		promoted, err := queries.PromoteAdmins(ctx, config.Admins)
+		if err != nil {
+			print("failed admins initialization: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Info("admins initialized", "promoted", promoted)
+	}
+
 	locales := map[string]map[string]string{
 		"es": i18n.ES,
 		"en": i18n.EN,
@@ -55,6 +73,12 @@ func main() {
 
 	s3client := s3.NewFromConfig(s3c)
 
+	filter, err := moderation.Load(config.ModerationDir)
+	if err != nil {
+		print("failed moderation wordlists initialization: %v\n", err)
+		os.Exit(1)
+	}
+
 	handler := handlers.New(
 		handlers.HandlerParams{
 			Production:   config.Production,
@@ -67,6 +91,8 @@ func main() {
 			ServerSecret: config.ServerSecret,
 			CookieName:   config.CookieName,
 			CookiePath:   config.Endpoints[config.RootPath],
+			PageCache:    pagecache.New(config.PageCacheSize),
+			Moderation:   filter,
 		},
 	)
 
@@ -74,19 +100,42 @@ func main() {
 
 	routes.Handle(
 		"GET "+config.Endpoints[config.AssetsPath],
-		middleware.DisableCacheInDevMode(
+		middleware.CacheControl(
 			config.Production,
+			config.AssetsCacheControl,
 			handlers.Gzip(http.FileServer(http.FS(assetsFS))),
 		),
 	)
 
+	jobs := scheduler.New(logger)
+	jobs.Every("site_schedules", time.Minute, handler.RunSiteSchedules)
+	jobs.Every("visits_flush", config.VisitsFlushInterval, handler.FlushVisits)
+	jobs.Every("visitors_prune", time.Hour, handler.PruneVisitors)
+	jobs.Every("trending", 15*time.Minute, handler.UpdateTrending)
+	jobs.Every("search_index", time.Minute, handler.IndexSites)
+	jobs.Every("reports_prune", time.Hour, handler.PruneReports)
+	jobs.Every("domains_prune", time.Hour, handler.PruneDomains)
+
+	done := make(chan struct{})
+	go func() {
+		jobs.Run(ctx)
+		close(done)
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// Custom domains are matched before any route, and always served at the
+	// root even when the app runs under a prefix
+	srv := &http.Server{
+		Addr:    ":" + config.Port,
+		Handler: handler.CustomDomainMiddleware(routes),
+	}
+
 	go func() {
-		logger.Info("server starting", "address", ":"+config.Port)
+		logger.Info("server starting", "address", srv.Addr)
 
-		if err := http.ListenAndServe(":"+config.Port, routes); err != nil {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("failed to start server", "port", config.Port, "error", err)
 			os.Exit(1)
 		}
@@ -96,4 +145,19 @@ func main() {
 
 	defer pool.Close()
 	logger.Info("shutting down...")
+
+	// Let in flight requests finish so their visits are counted
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down server", "error", err)
+	}
+
+	cancel()
+	<-done
+
+	if err := handler.FlushVisits(shutdownCtx); err != nil {
+		logger.Error("failed to flush visits", "error", err)
+	}
 }
@@ -4,12 +4,20 @@ import (
 	"net/http"
 )
 
-func DisableCacheInDevMode(production bool, next http.Handler) http.Handler {
-	if !production {
+// CacheControl sets the Cache-Control header to policy in production, an
+// empty policy leaves the header untouched. In development caching is always
+// disabled so rebuilt assets are picked up on reload
+func CacheControl(production bool, policy string, next http.Handler) http.Handler {
+	if production && policy == "" {
 		return next
 	}
+
+	if !production {
+		policy = "no-store"
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Cache-Control", policy)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// OperatorToken lets requests carrying token as a bearer token through, any
+// other request goes through fallback. An empty token is never accepted
+func OperatorToken(token string, fallback Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		guarded := fallback(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			guarded.ServeHTTP(w, r)
+		})
+	}
+}
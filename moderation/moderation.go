@@ -0,0 +1,291 @@
+// Package moderation matches user content against per-language wordlists.
+// Text is normalized before matching, so accents, case and common leetspeak
+// substitutions do not get around a list, and terms only match whole words.
+package moderation
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Verdict is the outcome of checking content, verdicts are ordered from the
+// least to the most severe.
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Flag
+	Reject
+)
+
+// Result is the verdict for some text along with the term that caused it
+// and the language of the list the term is in.
+type Result struct {
+	Verdict Verdict
+	Term    string
+	Lang    string
+}
+
+// List holds the terms of a language. Rejected and flagged terms may span
+// several words and end with * to match any word starting with them. Allowed
+// words are never matched, even when a term would match them.
+type List struct {
+	Reject []string
+	Flag   []string
+	Allow  []string
+}
+
+type term struct {
+	words  []string
+	prefix bool
+	source string
+}
+
+type wordlist struct {
+	reject []term
+	flag   []term
+	allow  map[string]bool
+}
+
+// Filter checks text against the lists of every language, since the
+// language of user content is not known.
+type Filter struct {
+	langs map[string]wordlist
+}
+
+//go:embed lists/*.txt
+var defaultLists embed.FS
+
+// New returns a filter for lists by language.
+func New(lists map[string]List) *Filter {
+	f := &Filter{langs: make(map[string]wordlist, len(lists))}
+
+	for lang, l := range lists {
+		wl := wordlist{allow: make(map[string]bool)}
+		for _, t := range l.Reject {
+			if t, ok := parseTerm(t); ok {
+				wl.reject = append(wl.reject, t)
+			}
+		}
+		for _, t := range l.Flag {
+			if t, ok := parseTerm(t); ok {
+				wl.flag = append(wl.flag, t)
+			}
+		}
+		for _, a := range l.Allow {
+			for _, w := range Words(a) {
+				wl.allow[w] = true
+			}
+		}
+		f.langs[lang] = wl
+	}
+
+	return f
+}
+
+// Load reads the lists of dir, or the default ones when dir is empty. Each
+// language is a <lang>.txt file with one "reject:", "flag:" or "allow:"
+// prefixed term per line, empty lines and lines starting with # are skipped.
+func Load(dir string) (*Filter, error) {
+	var fsys fs.FS = os.DirFS(dir)
+	if dir == "" {
+		sub, err := fs.Sub(defaultLists, "lists")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	files, err := fs.Glob(fsys, "*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make(map[string]List, len(files))
+	for _, name := range files {
+		l, err := readList(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		lists[strings.TrimSuffix(path.Base(name), ".txt")] = l
+	}
+
+	return New(lists), nil
+}
+
+func readList(fsys fs.FS, name string) (List, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return List{}, err
+	}
+	defer file.Close()
+
+	var l List
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return List{}, fmt.Errorf("%s:%d: missing reject:, flag: or allow: prefix", name, n)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(kind) {
+		case "reject":
+			l.Reject = append(l.Reject, value)
+		case "flag":
+			l.Flag = append(l.Flag, value)
+		case "allow":
+			l.Allow = append(l.Allow, value)
+		default:
+			return List{}, fmt.Errorf("%s:%d: unknown list %q", name, n, kind)
+		}
+	}
+
+	return l, scanner.Err()
+}
+
+func parseTerm(s string) (term, bool) {
+	s = strings.TrimSpace(s)
+	prefix := strings.HasSuffix(s, "*")
+
+	words := Words(strings.TrimSuffix(s, "*"))
+	if len(words) == 0 {
+		return term{}, false
+	}
+
+	return term{words: words, prefix: prefix, source: s}, true
+}
+
+// Check returns the most severe verdict for text across every language.
+func (f *Filter) Check(text string) Result {
+	words := Words(text)
+	result := Result{Verdict: Allow}
+
+	for lang, wl := range f.langs {
+		if r, ok := wl.match(words, wl.reject); ok {
+			return Result{Verdict: Reject, Term: r.source, Lang: lang}
+		}
+		if result.Verdict == Allow {
+			if r, ok := wl.match(words, wl.flag); ok {
+				result = Result{Verdict: Flag, Term: r.source, Lang: lang}
+			}
+		}
+	}
+
+	return result
+}
+
+// match returns the first of terms found in words
+func (wl wordlist) match(words []string, terms []term) (term, bool) {
+	for i := range words {
+		for _, t := range terms {
+			if wl.matchAt(words[i:], t) {
+				return t, true
+			}
+		}
+	}
+	return term{}, false
+}
+
+func (wl wordlist) matchAt(words []string, t term) bool {
+	if len(words) < len(t.words) {
+		return false
+	}
+
+	last := len(t.words) - 1
+	for i, w := range t.words {
+		if wl.allow[words[i]] {
+			return false
+		}
+		if i == last && t.prefix {
+			if !strings.HasPrefix(words[i], w) {
+				return false
+			}
+		} else if words[i] != w {
+			return false
+		}
+	}
+
+	return true
+}
+
+// leet maps the characters commonly used in place of letters back to them
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+}
+
+// punctuation reports whether r is neither part of a word nor a substitution
+func punctuation(r rune) bool {
+	_, ok := leet[r]
+	return !ok && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Words splits text into normalized words: lowercase, without accents and
+// with leetspeak substitutions undone. Numbers are kept as they are.
+func Words(text string) []string {
+	stripped, _, err := transform.String(
+		transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC),
+		text,
+	)
+	if err != nil {
+		stripped = text
+	}
+
+	var words []string
+
+	for _, field := range strings.Fields(strings.ToLower(stripped)) {
+		// Punctuation around a word is not a substitution, but substitutions
+		// can be at its edges, as in "$ex" or "a$$". A trailing ! ends a
+		// sentence more often than it stands for an i, as in "hi!"
+		field = strings.TrimLeftFunc(field, punctuation)
+		field = strings.TrimRightFunc(field, func(r rune) bool {
+			return r == '!' || punctuation(r)
+		})
+
+		if !strings.ContainsFunc(field, unicode.IsLetter) {
+			words = append(words, strings.FieldsFunc(field, func(r rune) bool {
+				return !unicode.IsDigit(r)
+			})...)
+			continue
+		}
+
+		mapped := strings.Map(func(r rune) rune {
+			if l, ok := leet[r]; ok {
+				return l
+			}
+			return r
+		}, field)
+
+		words = append(words, strings.FieldsFunc(mapped, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})...)
+	}
+
+	return words
+}
@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Hello World", want: []string{"hello", "world"}},
+		{text: "Café ÑANDÚ", want: []string{"cafe", "nandu"}},
+		{text: "h3ll0 w0rld", want: []string{"hello", "world"}},
+		{text: "$ex", want: []string{"sex"}},
+		{text: "@sshole", want: []string{"asshole"}},
+		{text: "a$$", want: []string{"ass"}},
+		{text: "!diot", want: []string{"idiot"}},
+		{text: "sh!t!", want: []string{"shit"}},
+		{text: "hi!", want: []string{"hi"}},
+		{text: "(hi)!", want: []string{"hi"}},
+		{text: "\"quoted,\" text.", want: []string{"quoted", "text"}},
+		{text: "p0rn-star", want: []string{"porn", "star"}},
+		{text: "2024 1337", want: []string{"2024", "1337"}},
+		{text: "!!! ...", want: nil},
+		{text: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := Words(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f := New(map[string]List{
+		"en": {
+			Reject: []string{"child porn*"},
+			Flag:   []string{"viagra", "casino*", "ass*", "free crypto*"},
+			Allow:  []string{"assess", "assistant"},
+		},
+		"es": {
+			Reject: []string{"estafa"},
+			Flag:   []string{"apuestas"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		text    string
+		verdict Verdict
+		term    string
+		lang    string
+	}{
+		{name: "clean", text: "a site about gardening", verdict: Allow},
+		{name: "whole word", text: "buy viagra now", verdict: Flag, term: "viagra", lang: "en"},
+		{name: "not a whole word", text: "viagras and viagraland", verdict: Allow},
+		{name: "prefix", text: "best casinos online", verdict: Flag, term: "casino*", lang: "en"},
+		{name: "prefix only at the start of words", text: "for the occasion", verdict: Allow},
+		{name: "leetspeak", text: "c4s1n0 night", verdict: Flag, term: "casino*", lang: "en"},
+		{name: "edge substitution", text: "nice @ss", verdict: Flag, term: "ass*", lang: "en"},
+		{name: "allowed words", text: "assess the assistant", verdict: Allow},
+		{name: "several words", text: "Free Crypto-giveaway", verdict: Flag, term: "free crypto*", lang: "en"},
+		{name: "words apart", text: "free tea and crypto", verdict: Allow},
+		{name: "reject", text: "child pornography", verdict: Reject, term: "child porn*", lang: "en"},
+		{name: "reject over an earlier flag", text: "viagra and child porn", verdict: Reject, term: "child porn*", lang: "en"},
+		{name: "reject in another language", text: "casino estafa", verdict: Reject, term: "estafa", lang: "es"},
+		{name: "flag in another language", text: "apuestas deportivas", verdict: Flag, term: "apuestas", lang: "es"},
+	}
+
+	for _, tt := range tests {
+		got := f.Check(tt.text)
+		want := Result{Verdict: tt.verdict, Term: tt.term, Lang: tt.lang}
+		if got != want {
+			t.Errorf("%s: Check(%q) = %+v, want %+v", tt.name, tt.text, got, want)
+		}
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	f, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := f.Check("child p0rn"); got.Verdict != Reject {
+		t.Errorf("Check(child p0rn) = %+v, want a rejection", got)
+	}
+	if got := f.Check("pornografía infantil"); got.Verdict != Reject || got.Lang != "es" {
+		t.Errorf("Check(pornografía infantil) = %+v, want an es rejection", got)
+	}
+	if got := f.Check("a blog about cooking"); got.Verdict != Allow {
+		t.Errorf("Check(a blog about cooking) = %+v, want Allow", got)
+	}
+}
@@ -0,0 +1,179 @@
+// Package pagecache implements a bounded LRU cache of rendered pages, stored
+// pre-compressed with gzip and brotli.
+package pagecache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+var (
+	// Hits and Misses count lookups across every cache, published through
+	// expvar as pagecache_hits and pagecache_misses.
+	Hits   = expvar.NewInt("pagecache_hits")
+	Misses = expvar.NewInt("pagecache_misses")
+)
+
+// Page is a rendered page along with the validators it was served with. Slug
+// is the site the page belongs to and SiteUser its owner, who is never served
+// cached pages.
+type Page struct {
+	Slug         string
+	SiteID       int64
+	SiteUser     int64
+	ETag         string
+	LastModified time.Time
+	Gzip         []byte
+	Brotli       []byte
+}
+
+// NewPage compresses html with both encodings.
+func NewPage(slug string, siteID, siteUser int64, etag string, lastModified time.Time, html []byte) (Page, error) {
+	var gz bytes.Buffer
+	gzw := gzip.NewWriter(&gz)
+	if _, err := gzw.Write(html); err != nil {
+		return Page{}, err
+	}
+	if err := gzw.Close(); err != nil {
+		return Page{}, err
+	}
+
+	var br bytes.Buffer
+	brw := brotli.NewWriterLevel(&br, brotli.DefaultCompression)
+	if _, err := brw.Write(html); err != nil {
+		return Page{}, err
+	}
+	if err := brw.Close(); err != nil {
+		return Page{}, err
+	}
+
+	return Page{
+		Slug:         slug,
+		SiteID:       siteID,
+		SiteUser:     siteUser,
+		ETag:         etag,
+		LastModified: lastModified,
+		Gzip:         gz.Bytes(),
+		Brotli:       br.Bytes(),
+	}, nil
+}
+
+type entry struct {
+	key  string
+	page Page
+}
+
+// Cache is safe for concurrent use. A cache with a non positive size stores
+// nothing.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	gen   uint64
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+func New(size int) *Cache {
+	return &Cache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the page stored under key and marks it as recently used.
+func (c *Cache) Get(key string) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		Misses.Add(1)
+		return Page{}, false
+	}
+
+	Hits.Add(1)
+	c.order.MoveToFront(el)
+
+	return el.Value.(*entry).page, true
+}
+
+// Generation changes on every invalidation. Callers read it before loading
+// the data a page is rendered from and hand it back to Put.
+func (c *Cache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// Put stores page under key, evicting the least recently used page when the
+// cache is full. The page is dropped if the cache was invalidated since
+// generation was read, as it may have been rendered from stale data.
+func (c *Cache) Put(key string, page Page, generation uint64) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.gen {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry).page = page
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, page: page})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+// Invalidate removes every page of the site with the given slug, whatever
+// locale, host or page it was stored under.
+func (c *Cache) Invalidate(slug string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	for key, el := range c.items {
+		if el.Value.(*entry).page.Slug == slug {
+			c.order.Remove(el)
+			delete(c.items, key)
+		}
+	}
+}
+
+// Purge removes every page.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	c.order.Init()
+	clear(c.items)
+}
+
+// Len returns the number of cached pages.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
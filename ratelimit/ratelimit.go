@@ -0,0 +1,67 @@
+// Package ratelimit limits how often a key, such as a client address, can do
+// something within a fixed window of time.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter allows up to limit events per key in every window. It is safe for
+// concurrent use.
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]window
+}
+
+func New(limit int, every time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  every,
+		windows: make(map[string]window),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit,
+// events over the limit are not recorded.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = window{start: now}
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+
+	w.count++
+	l.windows[key] = w
+
+	return true
+}
+
+// Prune forgets the keys whose window is over, it is meant to be run
+// periodically so the limiter does not grow with every key it has seen.
+func (l *Limiter) Prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
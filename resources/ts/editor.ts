@@ -3,7 +3,6 @@ import Header from '@editorjs/header'
 import List from '@editorjs/list'
 import Table from '@editorjs/table'
 import ImageTool from '@editorjs/image';
-import edjsHTML from 'editorjs-html'
 
 interface SiteData {
   title?: string;
@@ -13,12 +12,71 @@ interface SiteData {
 }
 
 let isFirstLoad = false
+// Slug of the page being edited, empty when editing the site itself
+let editorPage = ""
+
+function storageKey(site: string): string {
+  return editorPage ? `site:${site}/${editorPage}` : `site:${site}`;
+}
 let editorSyncTimeout = false;
 let editorModified = false
 
 const EDITOR_SYNC_REQUIRED_TIMEOUT = 10000;
 let editorSyncTimer: number | null = null;
 
+const EDITOR_DRAFT_SAVE_DELAY = 2000;
+let editorDraftTimer: number | null = null;
+let editorReady = false;
+
+function scheduleDraftSave(site: string, data: SiteData) {
+  // Ignore the events fired while the editor is being populated
+  if (!site || editorPage || !editorReady) return;
+
+  if (editorDraftTimer) clearTimeout(editorDraftTimer);
+
+  editorDraftTimer = window.setTimeout(() => {
+    saveDraft(site, data);
+  }, EDITOR_DRAFT_SAVE_DELAY);
+}
+
+async function saveDraft(site: string, data: SiteData) {
+  const csrfToken =
+    document.cookie
+      .split("; ")
+      .find((c) => c.startsWith("csrf="))
+      ?.split("=")[1] || "";
+
+  try {
+    const response = await fetch(`/editor/${encodeURIComponent(site)}`, {
+      method: "PUT",
+      credentials: "include",
+      headers: {
+        "Content-Type": "application/json",
+        "X-CSRF-Token": csrfToken ? csrfToken : "",
+      },
+      body: JSON.stringify({
+        title: data.title || "",
+        description: data.description || "",
+        slug: site,
+        content: getEditorContent(),
+      })
+    });
+
+    if (!response.ok) {
+      console.error("Draft error:", response.statusText);
+      return;
+    }
+
+    // The server answers with the updated draft status indicator
+    const statusEl = document.getElementById("editorDraftStatus");
+    if (statusEl) {
+      statusEl.outerHTML = await response.text();
+    }
+  } catch (err) {
+    console.error("Draft error:", err);
+  }
+}
+
 function startSyncTimeoutClock() {
   if (editorSyncTimer) clearTimeout(editorSyncTimer);
 
@@ -32,7 +90,7 @@ function startAutoSyncLoop(site: string) {
     if (!site) return;
 
     if (editorSyncTimeout === true && editorModified === true) {
-      const stored = localStorage.getItem(`site:${site}`);
+      const stored = localStorage.getItem(storageKey(site));
       const localData = stored ? JSON.parse(stored) : null;
       if (!localData) return;
 
@@ -44,67 +102,12 @@ function startAutoSyncLoop(site: string) {
   }, 1000);
 }
 
-function tableParser(block: {
-  data: {
-    withHeadings: boolean;
-    content: string[][];
-  };
-}): string {
-  const { withHeadings, content } = block.data;
-
-  if (!Array.isArray(content) || content.length === 0) {
-    return "";
-  }
-
-  let html = "<table>";
-
-  // if the first row should be used as a header
-  if (withHeadings) {
-    const headers = content[0];
-    html += "<thead><tr>";
-    headers.forEach(cell => {
-      html += `<th>${cell}</th>`;
-    });
-    html += "</tr></thead>";
-
-    // the rest of the rows go in the body
-    if (content.length > 1) {
-      html += "<tbody>";
-      for (let i = 1; i < content.length; i++) {
-        html += "<tr>";
-        content[i].forEach(cell => {
-          html += `<td>${cell}</td>`;
-        });
-        html += "</tr>";
-      }
-      html += "</tbody>";
-    }
-  } else {
-    // no headings, everything goes in <tbody>
-    html += "<tbody>";
-    content.forEach(row => {
-      html += "<tr>";
-      row.forEach(cell => {
-        html += `<td>${cell}</td>`;
-      });
-      html += "</tr>";
-    });
-    html += "</tbody>";
-  }
-
-  html += "</table>";
-  return html;
-}
-
-const edjsParser = edjsHTML({
-  table: tableParser,
-});
-
 export async function editorSyncer(
   localData: SiteData,
   site: string
 ): Promise<SiteData> {
-  if (!site) return localData;
+  // Pages are stored locally and saved on publish only
+  if (!site || editorPage) return localData;
 
   const csrfToken =
     document.cookie
@@ -149,11 +152,12 @@ export async function editorSyncer(
   }
 }
 
-export async function initEditor(site: string) {
+export async function initEditor(site: string, page: string = "") {
+  editorPage = page;
   let localData: SiteData | null = null;
 
   if (site) {
-    const stored = localStorage.getItem(`site:${site}`);
+    const stored = localStorage.getItem(storageKey(site));
     if (stored) {
       try {
         localData = JSON.parse(stored);
@@ -163,6 +167,19 @@ export async function initEditor(site: string) {
     }
   }
 
+  if (!localData) {
+    // Fall back to the draft or published content rendered by the server
+    const initialEl = document.getElementById('editor_initial');
+    if (initialEl?.textContent) {
+      try {
+        localData = JSON.parse(initialEl.textContent);
+        if (localData) localData.lastUpdated = 0;
+      } catch (e) {
+        console.warn("Invalid initial data for site:", site, e);
+      }
+    }
+  }
+
   if (!localData) {
     localData = {
       title: "",
@@ -233,16 +250,17 @@ export async function initEditor(site: string) {
 
     onReady: async () => {
       const output = await editor.save()
-      const outputHTML = edjsParser.parse(output)
-      const htmlEl = document.getElementById('editor_html') as HTMLTextAreaElement | null
-      if (htmlEl) {
-        htmlEl.value = Array.isArray(outputHTML) ? outputHTML.join('') : String(outputHTML)
+      const contentEl = document.getElementById('editor_content') as HTMLTextAreaElement | null
+      if (contentEl) {
+        contentEl.value = JSON.stringify(output)
       }
 
       const loaderEl = document.getElementById("editorLoader")
       if (loaderEl) {
         loaderEl.style.display = "none";
       }
+
+      editorReady = true;
     },
 
     onChange: async () => {
@@ -258,16 +276,15 @@ export async function initEditor(site: string) {
         lastUpdated: Date.now()
       };
 
-      localStorage.setItem(`site:${site}`, JSON.stringify(updated));
+      localStorage.setItem(storageKey(site), JSON.stringify(updated));
 
-      const outputHTML = edjsParser.parse(output);
-      const htmlEl = document.getElementById("editor_html") as HTMLTextAreaElement | null;
-      if (htmlEl) {
-        htmlEl.value = Array.isArray(outputHTML)
-          ? outputHTML.join("")
-          : String(outputHTML);
+      const contentEl = document.getElementById("editor_content") as HTMLTextAreaElement | null;
+      if (contentEl) {
+        contentEl.value = JSON.stringify(output);
       }
 
+      scheduleDraftSave(site, updated);
+
       if (editorSyncTimeout === true && editorModified === true) {
         editorSyncTimeout = false;
         editorModified = false;
@@ -296,7 +313,7 @@ function resizeAndRun(site: string, el: HTMLTextAreaElement) {
 
   if (!site) return;
 
-  const stored = localStorage.getItem(`site:${site}`);
+  const stored = localStorage.getItem(storageKey(site));
   const data = stored ? JSON.parse(stored) : {};
 
   // Use localStorage values on first load
@@ -313,7 +330,8 @@ function resizeAndRun(site: string, el: HTMLTextAreaElement) {
     } else if (el.id === 'editor_description') {
       data.description = el.value;
     }
-    localStorage.setItem(`site:${site}`, JSON.stringify(data));
+    localStorage.setItem(storageKey(site), JSON.stringify(data));
+    scheduleDraftSave(site, data);
   }
 
   // resize again after possible content change
@@ -321,7 +339,7 @@ function resizeAndRun(site: string, el: HTMLTextAreaElement) {
   el.style.height = el.scrollHeight + 'px';
 
   data.lastUpdated = Date.now();
-  localStorage.setItem(`site:${site}`, JSON.stringify(data));
+  localStorage.setItem(storageKey(site), JSON.stringify(data));
 
   editorModified = true;
 
@@ -334,11 +352,11 @@ function resizeAndRun(site: string, el: HTMLTextAreaElement) {
 }
 (window as any).resizeAndRun = resizeAndRun;
 
-export function getEditorHtml(): string {
-  const htmlEl = document.getElementById('editor_html') as HTMLTextAreaElement | null;
-  return htmlEl?.value || '';
+export function getEditorContent(): string {
+  const contentEl = document.getElementById('editor_content') as HTMLTextAreaElement | null;
+  return contentEl?.value || '';
 }
-(window as any).getEditorHtml = getEditorHtml;
+(window as any).getEditorContent = getEditorContent;
 
 /**
  * Uploads a file to the provided endpoint and returns Editor.js-compatible response.
@@ -2,6 +2,7 @@
 package router
 
 import (
+	"expvar"
 	"net/http"
 
 	"app/config"
@@ -17,6 +18,19 @@ func Routes(h *handlers.Handler) *http.ServeMux {
 	router.HandleFunc("GET "+config.Endpoints[config.TermsPath], h.Terms)
 
 	router.HandleFunc("GET "+config.Endpoints[config.SearchPath], h.Search)
+	router.HandleFunc("GET "+config.Endpoints[config.TagsPath]+"{tag}", h.TagPage)
+
+	router.HandleFunc("GET "+config.Endpoints[config.ReportPath]+"{site}", h.ReportForm)
+	router.HandleFunc("PUT "+config.Endpoints[config.ReportPath]+"{site}", h.Report)
+	router.HandleFunc("POST "+config.Endpoints[config.ReportPath]+"{site}", h.ReportConfirm)
+
+	router.HandleFunc("GET "+config.Endpoints[config.RobotsPath], h.Robots)
+	router.HandleFunc("GET "+config.Endpoints[config.SitemapPath], h.Sitemap)
+	router.HandleFunc("GET "+config.Endpoints[config.SitemapsPath]+"{chunk}", h.SitemapChunk)
+
+	router.HandleFunc("GET "+config.Endpoints[config.FeedsPath]+"{format}", h.Feed)
+	router.HandleFunc("GET "+config.Endpoints[config.FeedsPath]+"tags/{tag}/{format}", h.TagFeed)
+	router.HandleFunc("GET "+config.Endpoints[config.FeedsPath]+"sites/{site}/{format}", h.UserFeed)
 
 	router.HandleFunc("GET "+config.Endpoints[config.RegisterPath], h.RegisterForm)
 	router.HandleFunc("PUT "+config.Endpoints[config.RegisterPath], h.Register)
@@ -36,9 +50,15 @@ func Routes(h *handlers.Handler) *http.ServeMux {
 
 	router.Handle("GET "+config.Endpoints[config.PricingPath], middleware.With(loggedIn, h.Pricing))
 
-	router.Handle("GET "+config.Endpoints[config.EditorPath]+"{site...}", middleware.With(loggedIn, h.Editor))
+	router.Handle("GET "+config.Endpoints[config.EditorPath]+"{site}", middleware.With(loggedIn, h.Editor))
+	router.Handle("GET "+config.Endpoints[config.EditorPath]+"{site}/{page...}", middleware.With(loggedIn, h.PageEditor))
+	router.Handle("GET "+config.Endpoints[config.ExportPath]+"{site}", middleware.With(loggedIn, h.ExportSite))
+	router.Handle("GET "+config.Endpoints[config.MarkdownPath]+"{site}", middleware.With(loggedIn, h.ExportMarkdown))
 	router.Handle("GET "+config.Endpoints[config.DashboardPath], middleware.With(loggedIn, h.Dashboard))
+	router.Handle("GET "+config.Endpoints[config.AnalyticsPath]+"{site}", middleware.With(loggedIn, h.Analytics))
+	router.Handle("GET "+config.Endpoints[config.AnalyticsPath]+"{site}/referrers", middleware.With(loggedIn, h.AnalyticsReferrers))
 	router.Handle("GET "+config.Endpoints[config.AccountPath], middleware.With(loggedIn, h.Account))
+	router.Handle("GET "+config.Endpoints[config.ModerationPath], middleware.With(loggedIn, h.ModerationPage))
 	router.Handle("GET "+config.Endpoints[config.LogoutPath], middleware.With(loggedIn, h.Logout))
 
 	protected := middleware.Stack(
@@ -57,14 +77,20 @@ func Routes(h *handlers.Handler) *http.ServeMux {
 	router.Handle("POST "+config.Endpoints[config.UploadPath]+"{site}", middleware.With(protected, h.UploadImage))
 
 	router.HandleFunc("GET "+config.Endpoints[config.RootPath]+"{site}", h.Site)
+	router.HandleFunc("GET "+config.Endpoints[config.RootPath]+"{site}/{page...}", h.SitePage)
 
 	router.Handle("PATCH "+config.Endpoints[config.SettingsPath], middleware.With(protected, h.UpdateSettings))
 
+	router.Handle("PUT "+config.Endpoints[config.EditorPath]+"{site}", middleware.With(protected, h.SaveDraft))
 	router.Handle("PATCH "+config.Endpoints[config.EditorPath]+"{site}", middleware.With(protected, h.EditorSync))
 	router.Handle("DELETE "+config.Endpoints[config.EditorPath]+"{site}", middleware.With(protected, h.EditorUnpublish))
 
 	router.Handle("POST "+config.Endpoints[config.BannerPath]+"{site}", middleware.With(protected, h.UploadBanner))
 
+	router.Handle("GET "+config.Endpoints[config.RevisionsPath]+"{site}", middleware.With(protected, h.Revisions))
+	router.Handle("GET "+config.Endpoints[config.RevisionsPath]+"{site}/diff", middleware.With(protected, h.RevisionsDiff))
+	router.Handle("POST "+config.Endpoints[config.RevisionsPath]+"{site}/{revision}", middleware.With(protected, h.RestoreRevision))
+
 	router.Handle("POST "+config.Endpoints[config.CheckoutPath]+"create", middleware.With(protected, h.CreateOrder))
 	router.Handle("POST "+config.Endpoints[config.CheckoutPath]+"complete", middleware.With(protected, h.CompleteOrder))
 
@@ -73,6 +99,56 @@ func Routes(h *handlers.Handler) *http.ServeMux {
 	router.Handle("DELETE "+config.Endpoints[config.AccountPath]+"{email}", middleware.With(protected, h.DeleteAccount))
 
 	router.Handle("DELETE "+config.Endpoints[config.SettingsPath]+"{site}", middleware.With(protected, h.DeleteSite))
+	router.Handle("PATCH "+config.Endpoints[config.SettingsPath]+"{site}/tags/{tag}", middleware.With(protected, h.UpdateTagColor))
+
+	router.Handle("POST "+config.Endpoints[config.ModerationPath]+"{site}", middleware.With(protected, h.Takedown))
+	router.Handle("PATCH "+config.Endpoints[config.ModerationPath]+"reports/{report}", middleware.With(protected, h.DismissReport))
+	router.Handle("PATCH "+config.Endpoints[config.SchedulePath]+"{site}", middleware.With(protected, h.UpdateSchedule))
+
+	router.Handle("POST "+config.Endpoints[config.DomainsPath]+"{site}", middleware.With(protected, h.AddDomain))
+	router.Handle("PATCH "+config.Endpoints[config.DomainsPath]+"{site}/{domain}", middleware.With(protected, h.VerifyDomain))
+	router.Handle("DELETE "+config.Endpoints[config.DomainsPath]+"{site}/{domain}", middleware.With(protected, h.DeleteDomain))
+
+	router.Handle("POST "+config.Endpoints[config.PagesPath]+"{site}", middleware.With(protected, h.NewPage))
+	router.Handle("PUT "+config.Endpoints[config.PagesPath]+"{site}/{page...}", middleware.With(protected, h.PublishPage))
+	router.Handle("PATCH "+config.Endpoints[config.PagesPath]+"{site}/{page...}", middleware.With(protected, h.MovePage))
+	router.Handle("DELETE "+config.Endpoints[config.PagesPath]+"{site}/{page...}", middleware.With(protected, h.DeletePage))
+
+	router.Handle("POST "+config.Endpoints[config.MarkdownPath]+"{site}", middleware.With(protected, h.ImportMarkdown))
+
+	admin := middleware.Stack(
+		h.AuthenticationMiddleware(
+			false,
+			0,
+			config.Endpoints[config.LoginPath],
+		),
+		h.AdminMiddleware,
+	)
+
+	// Metrics are for operators, monitoring can read them with a token
+	metrics := middleware.OperatorToken(config.MetricsToken, admin)
+
+	router.Handle("GET "+config.Endpoints[config.MetricsPath], middleware.With(metrics, expvar.Handler().ServeHTTP))
+
+	router.Handle("GET "+config.Endpoints[config.AdminPath], middleware.With(admin, h.Admin))
+	router.Handle("GET "+config.Endpoints[config.AdminPath]+"users", middleware.With(admin, h.AdminSearchUsers))
+	router.Handle("GET "+config.Endpoints[config.AdminPath]+"users/{user}", middleware.With(admin, h.AdminUser))
+	router.Handle("GET "+config.Endpoints[config.AdminPath]+"sessions/{session}", middleware.With(admin, h.AdminImpersonate))
+	router.Handle("GET "+config.Endpoints[config.AdminPath]+"audit", middleware.With(admin, h.AdminAudit))
+
+	adminProtected := middleware.Stack(
+		h.AuthenticationMiddleware(
+			true,
+			0,
+			"",
+		),
+		h.AdminMiddleware,
+	)
+
+	router.Handle("PATCH "+config.Endpoints[config.AdminPath]+"users/{user}/plan", middleware.With(adminProtected, h.AdminUpdatePlan))
+	router.Handle("PATCH "+config.Endpoints[config.AdminPath]+"users/{user}/role", middleware.With(adminProtected, h.AdminUpdateRole))
+	router.Handle("DELETE "+config.Endpoints[config.AdminPath]+"sites/{site}", middleware.With(adminProtected, h.AdminUnpublishSite))
+	router.Handle("POST "+config.Endpoints[config.AdminPath]+"sites/{site}", middleware.With(adminProtected, h.AdminRestoreSite))
 
 	return router
 }
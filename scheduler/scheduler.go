@@ -0,0 +1,74 @@
+// Package scheduler implements a ticker-driven runner for background jobs.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work, it should return once ctx is done.
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Scheduler runs every registered job on its own interval.
+type Scheduler struct {
+	logger  *slog.Logger
+	entries []entry
+}
+
+// New creates an empty scheduler.
+func New(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Every registers job to run each interval. Jobs must be registered before
+// calling Run.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{
+		name:     name,
+		interval: interval,
+		job:      job,
+	})
+}
+
+// Run starts all jobs and blocks until ctx is done and every running job has
+// returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, e := range s.entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, e)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	s.logger.Debug("scheduled job started", "job", e.name, "interval", e.interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Debug("scheduled job stopped", "job", e.name)
+			return
+		case <-ticker.C:
+			if err := e.job(ctx); err != nil {
+				s.logger.Error("scheduled job failed", "job", e.name, "error", err)
+			}
+		}
+	}
+}
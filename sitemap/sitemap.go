@@ -0,0 +1,93 @@
+// Package sitemap writes sitemaps and sitemap indexes following the
+// sitemaps.org protocol.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// MaxURLs is the maximum number of URLs a single sitemap may list.
+const MaxURLs = 50000
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// ContentType is the media type sitemaps are served with.
+const ContentType = "application/xml; charset=utf-8"
+
+// URL is a single sitemap entry, a zero LastMod is omitted.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	XMLNS   string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+// Chunks returns how many sitemaps are needed to list total URLs, at least
+// one so the index is never empty.
+func Chunks(total int64) int64 {
+	if total <= 0 {
+		return 1
+	}
+	return (total + MaxURLs - 1) / MaxURLs
+}
+
+// WriteURLSet writes urls as a sitemap.
+func WriteURLSet(w io.Writer, urls []URL) error {
+	set := urlSet{XMLNS: namespace}
+
+	for _, u := range urls {
+		entry := urlEntry{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			entry.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	return write(w, set)
+}
+
+// WriteIndex writes a sitemap index listing the given sitemap locations.
+func WriteIndex(w io.Writer, locs []string) error {
+	index := sitemapIndex{XMLNS: namespace}
+
+	for _, loc := range locs {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: loc})
+	}
+
+	return write(w, index)
+}
+
+func write(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
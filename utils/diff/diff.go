@@ -0,0 +1,119 @@
+// Package diff implements a line based diff between two texts
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// MaxCells bounds the size of the table used to diff the lines that differ
+// between two texts, about 32MB of memory
+const MaxCells = 4 << 20
+
+// ErrTooLarge is returned when two texts differ in too many lines to be diffed
+var ErrTooLarge = errors.New("diff: texts too large")
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Split breaks a text into lines, ignoring a trailing newline
+func Split(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Lines returns the edit script that turns a into b, computed from the
+// longest common subsequence of both. The lines both texts share at the start
+// and the end are left out of the table, if the rest would need more than
+// MaxCells it returns ErrTooLarge
+func Lines(a, b []string) ([]Line, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	n, m := len(a)-prefix-suffix, len(b)-prefix-suffix
+	if (n+1)*(m+1) > MaxCells {
+		return nil, ErrTooLarge
+	}
+
+	lines := make([]Line, 0, max(len(a), len(b)))
+
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+
+	lines = append(lines, lcsLines(a[prefix:prefix+n], b[prefix:prefix+m])...)
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+
+	return lines, nil
+}
+
+func lcsLines(a, b []string) []Line {
+	n, m := len(a), len(b)
+
+	// lcs[i][j] holds the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, max(n, m))
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		lines = append(lines, Line{Op: Delete, Text: a[i]})
+	}
+
+	for ; j < m; j++ {
+		lines = append(lines, Line{Op: Insert, Text: b[j]})
+	}
+
+	return lines
+}
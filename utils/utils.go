@@ -9,9 +9,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 	"time"
 
+	"app/config"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -32,12 +37,90 @@ func HashCompare(hash, plain string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
 }
 
-func HostURL(r *http.Request) string {
-	scheme := "http"
+const (
+	forwardedProtoHeaderKey = "X-Forwarded-Proto"
+	forwardedHostHeaderKey  = "X-Forwarded-Host"
+	forwardedForHeaderKey   = "X-Forwarded-For"
+)
+
+// fromTrustedProxy reports whether r was sent by one of the reverse proxies
+// in config.TrustedProxies
+func fromTrustedProxy(r *http.Request) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	return trustedProxy(addrPort.Addr())
+}
+
+func trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, proxy := range config.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Host returns the host the client asked for, preferring the one set by a
+// trusted reverse proxy
+func Host(r *http.Request) string {
+	if host := r.Header.Get(forwardedHostHeaderKey); host != "" && fromTrustedProxy(r) {
+		return host
+	}
+	return r.Host
+}
+
+// Scheme returns the scheme the client used, preferring the one set by a
+// trusted reverse proxy
+func Scheme(r *http.Request) string {
+	if scheme := r.Header.Get(forwardedProtoHeaderKey); scheme != "" && fromTrustedProxy(r) {
+		return scheme
+	}
 	if r.TLS != nil {
-		scheme = "https"
+		return "https"
+	}
+	return "http"
+}
+
+// ClientIP returns the address of the client. Behind trusted reverse proxies
+// it is the last address of the X-Forwarded-For chain that is not a proxy,
+// the ones before it can be set by the client
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !fromTrustedProxy(r) {
+		return host
+	}
+
+	forwarded := r.Header.Values(forwardedForHeaderKey)
+	if len(forwarded) == 0 {
+		return host
 	}
-	return fmt.Sprintf("%s://%s", scheme, r.Host)
+
+	chain := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(chain[i])
+
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return host
+		}
+
+		if !trustedProxy(addr) || i == 0 {
+			return addr.Unmap().String()
+		}
+	}
+
+	return host
+}
+
+func HostURL(r *http.Request) string {
+	return fmt.Sprintf("%s://%s", Scheme(r), Host(r))
 }
 
 func InspectReader(r io.Reader) (mime string, size int64, data []byte, err error) {
@@ -68,6 +151,37 @@ func UnixToYMD(timestamp int64) string {
 	return t.Format("2006-01-02")
 }
 
+func UnixToYMDHM(timestamp int64) string {
+	loc, _ := time.LoadLocation("America/Costa_Rica")
+	t := time.Unix(timestamp, 0).In(loc)
+	return t.Format("2006-01-02 15:04")
+}
+
+// UnixToDateTimeLocal formats a timestamp as the value of a datetime-local
+// input, an empty string means the timestamp is not set
+func UnixToDateTimeLocal(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	loc, _ := time.LoadLocation("America/Costa_Rica")
+	t := time.Unix(timestamp, 0).In(loc)
+	return t.Format("2006-01-02T15:04")
+}
+
+// DateTimeLocalToUnix parses the value of a datetime-local input, an empty
+// string is parsed as 0
+func DateTimeLocalToUnix(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	loc, _ := time.LoadLocation("America/Costa_Rica")
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, loc)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
 func Gzip(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
 	gz := gzip.NewWriter(&buf)
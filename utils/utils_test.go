@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"app/config"
+)
+
+func TestClientIP(t *testing.T) {
+	config.TrustedProxies = []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("127.0.0.1/32"),
+	}
+	t.Cleanup(func() { config.TrustedProxies = nil })
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{name: "direct client", remote: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "direct client spoofing", remote: "192.0.2.1:1234", forwarded: []string{"198.51.100.7"}, want: "192.0.2.1"},
+		{name: "proxy without header", remote: "10.0.0.2:1234", want: "10.0.0.2"},
+		{name: "behind proxy", remote: "10.0.0.2:1234", forwarded: []string{"192.0.2.1"}, want: "192.0.2.1"},
+		{name: "behind proxies", remote: "127.0.0.1:1234", forwarded: []string{"192.0.2.1, 10.0.0.2"}, want: "192.0.2.1"},
+		{name: "client prepends", remote: "10.0.0.2:1234", forwarded: []string{"198.51.100.7, 192.0.2.1"}, want: "192.0.2.1"},
+		{name: "repeated headers", remote: "10.0.0.2:1234", forwarded: []string{"198.51.100.7", "192.0.2.1, 10.0.0.3"}, want: "192.0.2.1"},
+		{name: "only proxies", remote: "10.0.0.2:1234", forwarded: []string{"10.0.0.3, 10.0.0.4"}, want: "10.0.0.3"},
+		{name: "mapped address", remote: "[::ffff:10.0.0.2]:1234", forwarded: []string{"::ffff:192.0.2.1"}, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostAndScheme(t *testing.T) {
+	config.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	t.Cleanup(func() { config.TrustedProxies = nil })
+
+	r := httptest.NewRequest("GET", "http://app.example/", nil)
+	r.Header.Set("X-Forwarded-Host", "site.example")
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	r.RemoteAddr = "192.0.2.1:1234"
+	if got := HostURL(r); got != "http://app.example" {
+		t.Errorf("HostURL() from client = %q, want %q", got, "http://app.example")
+	}
+
+	r.RemoteAddr = "10.0.0.2:1234"
+	if got := HostURL(r); got != "https://site.example" {
+		t.Errorf("HostURL() from proxy = %q, want %q", got, "https://site.example")
+	}
+}
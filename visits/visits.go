@@ -0,0 +1,209 @@
+// Package visits buffers site visit counts in memory so page views do not
+// write to the database one by one.
+package visits
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// MaxVisitors and MaxSources bound the visitors and sources a Counter keeps
+// until they are flushed, so they cannot grow without limit while flushes
+// fail. Site totals are always kept, there is one per site.
+const (
+	MaxVisitors = 100_000
+	MaxSources  = 10_000
+)
+
+// Dropped counts visitors and sources that did not fit in a Counter, across
+// every counter, published through expvar as visits_dropped.
+var Dropped = expvar.NewInt("visits_dropped")
+
+// Visitor identifies a unique visitor of a site on the day starting at
+// DayUnix.
+type Visitor struct {
+	Site    int64
+	DayUnix int64
+	ID      string
+}
+
+// Source is where a visit to Site on the day starting at DayUnix came from,
+// the host of the referrer and the utm campaign parameters. The zero Source
+// is a direct visit.
+type Source struct {
+	Site        int64
+	DayUnix     int64
+	Referrer    string
+	UTMSource   string
+	UTMMedium   string
+	UTMCampaign string
+}
+
+// Batch is everything recorded between two flushes. Counts are keyed by site
+// id, and sources by where they came from.
+type Batch struct {
+	Counts   map[int64]int64
+	Visitors []Visitor
+	Sources  map[Source]int64
+}
+
+// FlushFunc persists a batch. It must either store everything or nothing.
+type FlushFunc func(ctx context.Context, batch Batch) error
+
+// Counter collects visits per site until they are flushed. It is safe for
+// concurrent use.
+type Counter struct {
+	mu       sync.Mutex
+	counts   map[int64]int64
+	visitors map[Visitor]struct{}
+	sources  map[Source]int64
+	flush    FlushFunc
+
+	maxVisitors     int
+	maxSources      int
+	droppedVisitors int64
+	droppedSources  int64
+
+	// flushing serializes flushes so counts put back after a failed flush
+	// are never written twice
+	flushing sync.Mutex
+}
+
+func New(flush FlushFunc) *Counter {
+	return &Counter{
+		counts:   make(map[int64]int64),
+		visitors: make(map[Visitor]struct{}),
+		sources:  make(map[Source]int64),
+		flush:    flush,
+
+		maxVisitors: MaxVisitors,
+		maxSources:  MaxSources,
+	}
+}
+
+// Add records a visit to site. Visitors seen more than once between flushes
+// are only handed once to the flush function, and direct visits, with an
+// empty source, are only counted in the site totals.
+func (c *Counter) Add(site int64, visitor Visitor, source Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[site]++
+	c.addVisitor(visitor)
+
+	if source.Referrer != "" || source.UTMSource != "" || source.UTMMedium != "" || source.UTMCampaign != "" {
+		c.addSource(source, 1)
+	}
+}
+
+// addVisitor and addSource drop what does not fit, c.mu must be held
+func (c *Counter) addVisitor(visitor Visitor) {
+	if _, ok := c.visitors[visitor]; !ok && len(c.visitors) >= c.maxVisitors {
+		c.droppedVisitors++
+		Dropped.Add(1)
+		return
+	}
+	c.visitors[visitor] = struct{}{}
+}
+
+func (c *Counter) addSource(source Source, n int64) {
+	if _, ok := c.sources[source]; !ok && len(c.sources) >= c.maxSources {
+		c.droppedSources++
+		Dropped.Add(1)
+		return
+	}
+	c.sources[source] += n
+}
+
+// TakeDropped returns how many visitors and sources were dropped since it was
+// last called.
+func (c *Counter) TakeDropped() (visitors, sources int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	visitors, sources = c.droppedVisitors, c.droppedSources
+	c.droppedVisitors, c.droppedSources = 0, 0
+	return visitors, sources
+}
+
+// Pending returns the number of visits not flushed yet.
+func (c *Counter) Pending() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for _, n := range c.counts {
+		total += n
+	}
+	return total
+}
+
+// Flush hands every pending count, visitor and source to the flush function.
+// Visits recorded while flushing are kept for the next flush, and on error the
+// flushed ones are put back so they are retried, as long as they fit.
+func (c *Counter) Flush(ctx context.Context) error {
+	c.flushing.Lock()
+	defer c.flushing.Unlock()
+
+	c.mu.Lock()
+	counts, visitors, sources := c.counts, c.visitors, c.sources
+	c.counts = make(map[int64]int64, len(counts))
+	c.visitors = make(map[Visitor]struct{}, len(visitors))
+	c.sources = make(map[Source]int64, len(sources))
+	c.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	batch := Batch{
+		Counts:   counts,
+		Visitors: make([]Visitor, 0, len(visitors)),
+		Sources:  sources,
+	}
+
+	for v := range visitors {
+		batch.Visitors = append(batch.Visitors, v)
+	}
+
+	if err := c.flush(ctx, batch); err != nil {
+		c.mu.Lock()
+		for site, n := range counts {
+			c.counts[site] += n
+		}
+		for v := range visitors {
+			c.addVisitor(v)
+		}
+		for source, n := range sources {
+			c.addSource(source, n)
+		}
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// NewVisitor identifies whoever sent a request from ip with userAgent to site
+// on day, without keeping either. They are hashed with a salt derived from
+// secret that changes every day, so ids cannot be linked across days.
+func NewVisitor(secret string, site int64, day time.Time, ip, userAgent string) Visitor {
+	day = day.UTC().Truncate(24 * time.Hour)
+
+	salt := hmac.New(sha256.New, []byte(secret))
+	salt.Write([]byte("visitors:" + day.Format(time.DateOnly)))
+
+	id := hmac.New(sha256.New, salt.Sum(nil))
+	id.Write([]byte(ip + "\x00" + userAgent))
+
+	return Visitor{
+		Site:    site,
+		DayUnix: day.Unix(),
+		ID:      hex.EncodeToString(id.Sum(nil)[:16]),
+	}
+}
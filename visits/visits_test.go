@@ -0,0 +1,244 @@
+package visits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errFlush = errors.New("flush failed")
+
+// store keeps what a Counter flushed, failing every other flush when flaky
+type store struct {
+	mu       sync.Mutex
+	flaky    bool
+	calls    int
+	counts   map[int64]int64
+	visitors map[Visitor]int
+	sources  map[Source]int64
+}
+
+func newStore(flaky bool) *store {
+	return &store{
+		flaky:    flaky,
+		counts:   make(map[int64]int64),
+		visitors: make(map[Visitor]int),
+		sources:  make(map[Source]int64),
+	}
+}
+
+func (s *store) flush(ctx context.Context, batch Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.flaky && s.calls%2 == 1 {
+		return errFlush
+	}
+
+	for site, n := range batch.Counts {
+		s.counts[site] += n
+	}
+	for _, v := range batch.Visitors {
+		s.visitors[v]++
+	}
+	for source, n := range batch.Sources {
+		s.sources[source] += n
+	}
+	return nil
+}
+
+func TestCounterConcurrentAddAndFlush(t *testing.T) {
+	const (
+		writers = 16
+		visits  = 500
+		sites   = 4
+	)
+
+	s := newStore(true)
+	c := New(s.flush)
+	ctx := context.Background()
+
+	stop := make(chan struct{})
+	flushed := make(chan struct{})
+
+	go func() {
+		defer close(flushed)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if err := c.Flush(ctx); err != nil && !errors.Is(err, errFlush) {
+					t.Errorf("Flush() error = %v", err)
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Go(func() {
+			for i := range visits {
+				site := int64(i % sites)
+				c.Add(
+					site,
+					Visitor{Site: site, ID: string(rune('a' + w))},
+					Source{Site: site, Referrer: "example.com"},
+				)
+			}
+		})
+	}
+	wg.Wait()
+
+	close(stop)
+	<-flushed
+
+	// Every other flush fails, the second one in a row always stores what
+	// is left
+	for c.Pending() > 0 {
+		if err := c.Flush(ctx); err != nil && !errors.Is(err, errFlush) {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+
+	var total int64
+	for site := range int64(sites) {
+		want := int64(writers * visits / sites)
+		if got := s.counts[site]; got != want {
+			t.Errorf("site %d flushed %d visits, want %d", site, got, want)
+		}
+		if got := s.sources[Source{Site: site, Referrer: "example.com"}]; got != want {
+			t.Errorf("site %d flushed %d referred visits, want %d", site, got, want)
+		}
+		total += s.counts[site]
+	}
+
+	if total != writers*visits {
+		t.Errorf("flushed %d visits, want %d", total, writers*visits)
+	}
+
+	if len(s.visitors) != writers*sites {
+		t.Errorf("flushed %d visitors, want %d", len(s.visitors), writers*sites)
+	}
+}
+
+func TestCounterFailedFlushKeepsVisits(t *testing.T) {
+	s := newStore(true)
+	c := New(s.flush)
+	ctx := context.Background()
+
+	visitor := Visitor{Site: 1, DayUnix: 86400, ID: "visitor"}
+
+	c.Add(1, visitor, Source{Site: 1, UTMSource: "newsletter"})
+	c.Add(1, visitor, Source{})
+	c.Add(2, Visitor{Site: 2, DayUnix: 86400, ID: "visitor"}, Source{})
+
+	if err := c.Flush(ctx); !errors.Is(err, errFlush) {
+		t.Fatalf("Flush() error = %v, want %v", err, errFlush)
+	}
+
+	if got := c.Pending(); got != 3 {
+		t.Fatalf("Pending() after failed flush = %d, want 3", got)
+	}
+
+	c.Add(1, visitor, Source{})
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if got := c.Pending(); got != 0 {
+		t.Errorf("Pending() after flush = %d, want 0", got)
+	}
+	if s.counts[1] != 3 || s.counts[2] != 1 {
+		t.Errorf("flushed counts = %v, want map[1:3 2:1]", s.counts)
+	}
+	if n := s.visitors[visitor]; n != 1 {
+		t.Errorf("visitor flushed %d times, want 1", n)
+	}
+	if n := s.sources[Source{Site: 1, UTMSource: "newsletter"}]; n != 1 {
+		t.Errorf("source flushed %d times, want 1", n)
+	}
+	if _, ok := s.sources[Source{}]; ok {
+		t.Error("direct visits flushed as a source")
+	}
+}
+
+func TestCounterBoundsFailedFlushes(t *testing.T) {
+	s := newStore(false)
+	failing := func(ctx context.Context, batch Batch) error { return errFlush }
+	c := New(failing)
+	c.maxVisitors, c.maxSources = 3, 2
+	ctx := context.Background()
+
+	// The database is down for several flushes, new visitors keep arriving
+	for round := range 3 {
+		for i := range 2 {
+			id := string(rune('a' + round*2 + i))
+			c.Add(1, Visitor{Site: 1, ID: id}, Source{Site: 1, Referrer: id + ".com"})
+		}
+		if err := c.Flush(ctx); !errors.Is(err, errFlush) {
+			t.Fatalf("Flush() error = %v, want %v", err, errFlush)
+		}
+	}
+
+	visitors, sources := c.TakeDropped()
+	if visitors != 3 || sources != 4 {
+		t.Errorf("TakeDropped() = %d, %d, want 3, 4", visitors, sources)
+	}
+	if visitors, sources := c.TakeDropped(); visitors != 0 || sources != 0 {
+		t.Errorf("TakeDropped() again = %d, %d, want 0, 0", visitors, sources)
+	}
+
+	c.flush = s.flush
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if s.counts[1] != 6 {
+		t.Errorf("flushed %d visits, want every one of the 6", s.counts[1])
+	}
+	if len(s.visitors) != 3 {
+		t.Errorf("flushed %d visitors, want 3", len(s.visitors))
+	}
+	if len(s.sources) != 2 {
+		t.Errorf("flushed %d sources, want 2", len(s.sources))
+	}
+}
+
+func TestCounterFlushEmpty(t *testing.T) {
+	s := newStore(false)
+	c := New(s.flush)
+
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if s.calls != 0 {
+		t.Errorf("flush func called %d times with nothing pending", s.calls)
+	}
+}
+
+func TestNewVisitor(t *testing.T) {
+	day := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	a := NewVisitor("secret", 1, day, "192.0.2.1", "agent")
+	b := NewVisitor("secret", 1, day.Add(time.Hour), "192.0.2.1", "agent")
+	if a != b {
+		t.Errorf("same visitor on the same day got %v and %v", a, b)
+	}
+
+	if a.DayUnix != time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("DayUnix = %d, want the start of the day", a.DayUnix)
+	}
+
+	if c := NewVisitor("secret", 1, day.AddDate(0, 0, 1), "192.0.2.1", "agent"); c.ID == a.ID {
+		t.Error("visitor id did not change across days")
+	}
+
+	if c := NewVisitor("secret", 1, day, "192.0.2.2", "agent"); c.ID == a.ID {
+		t.Error("different clients got the same visitor id")
+	}
+}